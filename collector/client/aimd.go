@@ -0,0 +1,145 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	aimdInitialLimit = 4
+	aimdMinLimit     = 1
+	aimdMaxLimit     = 64
+	// aimdBackoff The factor applied to the limit on an overload or a latency above aimdLatencyTolerance times the baseline
+	aimdBackoff          = 0.5
+	aimdLatencyTolerance = 2
+	// aimdBaselineDrift How fast the baseline latency follows latencies above it, so that it recovers from an unusually fast call
+	aimdBaselineDrift = 0.01
+)
+
+var generateConcurrency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "powerstore_api_generate_concurrency_limit",
+	Help: "Number of metrics/generate requests allowed at once by the adaptive limiter of the powerstore",
+}, []string{"IP"})
+
+func init() {
+	prometheus.MustRegister(generateConcurrency)
+}
+
+// aimdLimiter Limits the concurrent metrics/generate requests of a powerstore. The limit grows by one per limit
+// of calls answered within the latency tolerance and is halved on an overload or a latency rise, at most once per
+// latency so that the calls failed together back off once.
+type aimdLimiter struct {
+	ip       string
+	lock     sync.Mutex
+	limit    float64
+	inflight int
+	waiting  []chan struct{}
+	baseline time.Duration
+	lastCut  time.Time
+}
+
+func newAimdLimiter(ip string) *aimdLimiter {
+	generateConcurrency.WithLabelValues(ip).Set(aimdInitialLimit)
+	return &aimdLimiter{ip: ip, limit: aimdInitialLimit}
+}
+
+// acquire Wait until the request is allowed, the returned release must be called with its latency and error
+func (l *aimdLimiter) acquire(ctx context.Context) (func(time.Duration, error), error) {
+	l.lock.Lock()
+	if l.inflight < int(l.limit) {
+		l.inflight++
+		l.lock.Unlock()
+		return l.release, nil
+	}
+	ready := make(chan struct{})
+	l.waiting = append(l.waiting, ready)
+	l.lock.Unlock()
+	select {
+	case <-ready:
+		return l.release, nil
+	case <-ctx.Done():
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		for i, waiting := range l.waiting {
+			if waiting == ready {
+				l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// allowed at the same time, let the next request go
+		l.inflight--
+		l.wake()
+		return nil, ctx.Err()
+	}
+}
+
+func (l *aimdLimiter) release(latency time.Duration, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.inflight--
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// given up by the caller, says nothing about the powerstore
+	case overloaded(err) || l.baseline > 0 && latency > aimdLatencyTolerance*l.baseline:
+		if time.Since(l.lastCut) > latency {
+			l.limit *= aimdBackoff
+			if l.limit < aimdMinLimit {
+				l.limit = aimdMinLimit
+			}
+			l.lastCut = time.Now()
+		}
+	default:
+		if l.limit < aimdMaxLimit {
+			l.limit += 1 / l.limit
+		}
+	}
+	if err == nil {
+		if l.baseline == 0 || latency < l.baseline {
+			l.baseline = latency
+		} else {
+			l.baseline += time.Duration(float64(latency-l.baseline) * aimdBaselineDrift)
+		}
+	}
+	generateConcurrency.WithLabelValues(l.ip).Set(float64(int(l.limit)))
+	l.wake()
+}
+
+// wake Let the waiting requests go up to the limit, the lock must be held
+func (l *aimdLimiter) wake() {
+	for len(l.waiting) > 0 && l.inflight < int(l.limit) {
+		l.inflight++
+		close(l.waiting[0])
+		l.waiting = l.waiting[1:]
+	}
+}
+
+// overloaded Whether the error tells that the powerstore may be overloaded: a 429 or 5xx status or no answer.
+// The other error statuses, e.g. 400 or 404, are answers to the request itself.
+func overloaded(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusTooManyRequests || statusErr.Code >= http.StatusInternalServerError
+	}
+	return retryable(err)
+}
@@ -0,0 +1,193 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// call Acquire the limiter and release it with the latency and error of a request
+func call(t *testing.T, l *aimdLimiter, latency time.Duration, err error) {
+	t.Helper()
+	release, acquireErr := l.acquire(context.Background())
+	if acquireErr != nil {
+		t.Fatal(acquireErr)
+	}
+	release(latency, err)
+}
+
+// limitOf The limit of the limiter, the powerstore_api_generate_concurrency_limit is checked to match it
+func limitOf(t *testing.T, l *aimdLimiter) int {
+	t.Helper()
+	l.lock.Lock()
+	limit := int(l.limit)
+	l.lock.Unlock()
+	if gauge := testutil.ToFloat64(generateConcurrency.WithLabelValues(l.ip)); int(gauge) != limit {
+		t.Errorf("powerstore_api_generate_concurrency_limit = %v, want %d", gauge, limit)
+	}
+	return limit
+}
+
+func TestAimdIncrease(t *testing.T) {
+	l := newAimdLimiter("aimd-increase")
+	// the limit grows by 1/limit per call answered in time, about one per limit of calls
+	for i := 0; i < aimdInitialLimit; i++ {
+		call(t, l, 10*time.Millisecond, nil)
+	}
+	if limit := limitOf(t, l); limit != aimdInitialLimit {
+		t.Errorf("limit = %d after %d calls, want %d", limit, aimdInitialLimit, aimdInitialLimit)
+	}
+	call(t, l, 10*time.Millisecond, nil)
+	if limit := limitOf(t, l); limit != aimdInitialLimit+1 {
+		t.Errorf("limit = %d after %d calls, want %d", limit, aimdInitialLimit+1, aimdInitialLimit+1)
+	}
+	for i := 0; i < 10000; i++ {
+		call(t, l, 10*time.Millisecond, nil)
+	}
+	if limit := limitOf(t, l); limit != aimdMaxLimit {
+		t.Errorf("limit = %d, want the max %d", limit, aimdMaxLimit)
+	}
+}
+
+func TestAimdHalveOnError(t *testing.T) {
+	unavailable := &StatusError{Code: http.StatusServiceUnavailable, message: "the powerstore answered 503"}
+	l := newAimdLimiter("aimd-error")
+	call(t, l, time.Millisecond, unavailable)
+	if limit := limitOf(t, l); limit != aimdInitialLimit/2 {
+		t.Errorf("limit = %d after an error, want %d", limit, aimdInitialLimit/2)
+	}
+	// the calls failed together back off once
+	call(t, l, time.Second, unavailable)
+	if limit := limitOf(t, l); limit != aimdInitialLimit/2 {
+		t.Errorf("limit = %d after a second error within its latency, want %d", limit, aimdInitialLimit/2)
+	}
+	time.Sleep(5 * time.Millisecond)
+	call(t, l, time.Millisecond, unavailable)
+	call(t, l, time.Millisecond, unavailable)
+	if limit := limitOf(t, l); limit != aimdMinLimit {
+		t.Errorf("limit = %d after repeated errors, want the min %d", limit, aimdMinLimit)
+	}
+
+	// the requests given up by the caller do not count
+	l = newAimdLimiter("aimd-cancel")
+	call(t, l, time.Millisecond, context.Canceled)
+	call(t, l, time.Millisecond, context.DeadlineExceeded)
+	if limit := limitOf(t, l); limit != aimdInitialLimit {
+		t.Errorf("limit = %d after cancelled calls, want %d", limit, aimdInitialLimit)
+	}
+}
+
+func TestAimdOverload(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		cut  bool
+	}{
+		{"answered", nil, false},
+		{"bad request", &StatusError{Code: http.StatusBadRequest}, false},
+		{"unauthorized", &StatusError{Code: http.StatusUnauthorized}, false},
+		{"not found", &StatusError{Code: http.StatusNotFound}, false},
+		{"unprocessable", &StatusError{Code: http.StatusUnprocessableEntity}, false},
+		{"too many requests", &StatusError{Code: http.StatusTooManyRequests}, true},
+		{"internal error", &StatusError{Code: http.StatusInternalServerError}, true},
+		{"bad gateway", &StatusError{Code: http.StatusBadGateway}, true},
+		{"unavailable", &StatusError{Code: http.StatusServiceUnavailable}, true},
+		{"gateway timeout", &StatusError{Code: http.StatusGatewayTimeout}, true},
+		{"connection refused", dialError, true},
+		{"truncated body", fmt.Errorf("get resource error: %w", io.ErrUnexpectedEOF), true},
+		{"handshake", &url.Error{Op: "Post", URL: "https://10.0.0.1", Err: &HandshakeError{Err: errors.New("bad certificate")}}, false},
+		{"cancelled", context.Canceled, false},
+		{"deadline", context.DeadlineExceeded, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newAimdLimiter("aimd-overload-" + test.name)
+			call(t, l, time.Millisecond, test.err)
+			want := aimdInitialLimit
+			if test.cut {
+				want = aimdInitialLimit / 2
+			}
+			if limit := limitOf(t, l); limit != want {
+				t.Errorf("limit = %d after %v, want %d", limit, test.err, want)
+			}
+		})
+	}
+}
+
+func TestAimdHalveOnLatency(t *testing.T) {
+	l := newAimdLimiter("aimd-latency")
+	call(t, l, 10*time.Millisecond, nil)
+	call(t, l, aimdLatencyTolerance*10*time.Millisecond, nil)
+	if limit := limitOf(t, l); limit < aimdInitialLimit {
+		t.Errorf("limit = %d after a latency at the tolerance, want no cut", limit)
+	}
+	time.Sleep(25 * time.Millisecond)
+	call(t, l, 25*time.Millisecond, nil)
+	if limit := limitOf(t, l); limit != aimdInitialLimit/2 {
+		t.Errorf("limit = %d after a latency above %d times the baseline, want %d", limit, aimdLatencyTolerance, aimdInitialLimit/2)
+	}
+}
+
+func TestAimdQueue(t *testing.T) {
+	l := newAimdLimiter("aimd-queue")
+	var releases []func(time.Duration, error)
+	for i := 0; i < aimdInitialLimit; i++ {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request beyond the limit got %v, want to wait", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		release, err := l.acquire(context.Background())
+		if err == nil {
+			release(time.Millisecond, nil)
+		}
+		acquired <- err
+	}()
+	releases[0](time.Millisecond, nil)
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting request was not let go by the release")
+	}
+	for _, release := range releases[1:] {
+		release(time.Millisecond, nil)
+	}
+	if l.inflight != 0 {
+		t.Errorf("inflight = %d after every release, want 0", l.inflight)
+	}
+}
@@ -17,12 +17,12 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/go-kit/log"
-	"github.com/go-kit/log/level"
-	"github.com/tidwall/gjson"
+	"fmt"
 	"powerstore-metrics-exporter/utils"
 	"strconv"
+	"strings"
 )
 
 type RequestBody struct {
@@ -31,286 +31,247 @@ type RequestBody struct {
 	Interval string `json:"interval"`
 }
 
-// PowerstoreModuleID This map stores the mapping relationships of the ip, module type, module id, and module name of the powerstore
-var PowerstoreModuleID = make(map[string]map[string]map[string]gjson.Result)
+// The entities of metrics/generate
+const (
+	EntityAppliancePerformance  = "performance_metrics_by_appliance"
+	EntityNodePerformance       = "performance_metrics_by_node"
+	EntityVolumePerformance     = "performance_metrics_by_volume"
+	EntityVgPerformance         = "performance_metrics_by_vg"
+	EntityFcPortPerformance     = "performance_metrics_by_fe_fc_port"
+	EntityEthPortPerformance    = "performance_metrics_by_fe_eth_port"
+	EntityNasPerformance        = "performance_metrics_by_nas_server"
+	EntityFilesystemPerformance = "performance_metrics_by_file_system"
+	EntityApplianceSpace        = "space_metrics_by_appliance"
+	EntityFilesystemSpace       = "space_metrics_by_file_system"
+	EntityDriveWear             = "wear_metrics_by_drive"
+)
+
+// The intervals of metrics/generate
+const (
+	IntervalTwentySec = "Twenty_Sec"
+	IntervalFiveMins  = "Five_Mins"
+	IntervalOneHour   = "One_Hour"
+	IntervalOneDay    = "One_Day"
+)
 
-func (c *Client) getData(path, method, body string) (string, error) {
-	utils.ReqCounter <- 1
-	result, err := c.getResource(method, path, body)
-	<-utils.ReqCounter
+// entityIntervals The intervals supported by each entity of metrics/generate
+var entityIntervals = map[string][]string{
+	EntityAppliancePerformance:  {IntervalTwentySec, IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityNodePerformance:       {IntervalTwentySec, IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityVolumePerformance:     {IntervalTwentySec, IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityVgPerformance:         {IntervalTwentySec, IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityFcPortPerformance:     {IntervalTwentySec, IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityEthPortPerformance:    {IntervalTwentySec, IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityNasPerformance:        {IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityFilesystemPerformance: {IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityApplianceSpace:        {IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityFilesystemSpace:       {IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+	EntityDriveWear:             {IntervalFiveMins, IntervalOneHour, IntervalOneDay},
+}
+
+// CheckInterval Returns an error when the entity of metrics/generate does not support the interval
+func CheckInterval(entity, interval string) error {
+	intervals, ok := entityIntervals[entity]
+	if !ok {
+		return fmt.Errorf("unknown metrics entity %q", entity)
+	}
+	for _, supported := range intervals {
+		if interval == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("interval %q is not supported by %s, supported intervals are %s", interval, entity, strings.Join(intervals, ", "))
+}
+
+// getData Returns the response body of the request, shared with the identical requests in flight or cached
+func (c *Client) getData(ctx context.Context, path, method, body string) (string, error) {
+	return c.cache.do(ctx, method+" "+path+" "+body, func(ctx context.Context) (string, error) {
+		return c.sendData(ctx, path, method, body)
+	})
+}
+
+// sendData Send the request, all the pages of a collection are fetched and merged
+func (c *Client) sendData(ctx context.Context, path, method, body string) (string, error) {
+	if method == "GET" {
+		return c.getCollection(ctx, path)
+	}
+	result, _, err := c.getResource(ctx, method, path, body)
 	return result, err
 }
 
-func (c *Client) GetCluster() (string, error) {
-	return c.getData("cluster?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetCluster(ctx context.Context) ([]Cluster, error) {
+	return getList[Cluster](ctx, c, "cluster", "cluster?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetPort(portType string) (string, error) {
-	return c.getData(portType+"?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetPort(ctx context.Context, portType string) ([]Port, error) {
+	return getList[Port](ctx, c, portType, portType+"?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetHardware(hardwareType string) (string, error) {
-	return c.getData("hardware?select=*&type=eq."+hardwareType+"&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetHardware(ctx context.Context, hardwareType string) ([]Hardware, error) {
+	return getList[Hardware](ctx, c, "hardware", "hardware?select=*&type=eq."+hardwareType+"&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetVolume() (string, error) {
+func (c *Client) GetVolume(ctx context.Context) ([]Volume, error) {
 	if c.version == "v3" {
-		return c.getData("volume_list_cma_view?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+		return getList[Volume](ctx, c, "volume_list_cma_view", "volume_list_cma_view?select=*&limit="+strconv.Itoa(c.limit))
 	}
-	return c.getData("volume?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+	return getList[Volume](ctx, c, "volume", "volume?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetAppliance() (string, error) {
-	return c.getData("appliance?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetAppliance(ctx context.Context) ([]Appliance, error) {
+	return getList[Appliance](ctx, c, "appliance", "appliance?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetNas() (string, error) {
-	return c.getData("nas_server?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetNas(ctx context.Context) ([]NasServer, error) {
+	return getList[NasServer](ctx, c, "nas_server", "nas_server?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetNasDetail() (string, error) {
-	return c.getData("nas_server_list_cma_view?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetNasDetail(ctx context.Context) ([]NasServer, error) {
+	return getList[NasServer](ctx, c, "nas_server_list_cma_view", "nas_server_list_cma_view?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetVolumeGroup() (string, error) {
-	return c.getData("volume_group_list_cma_view?select=*&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetVolumeGroup(ctx context.Context) ([]VolumeGroup, error) {
+	return getList[VolumeGroup](ctx, c, "volume_group_list_cma_view", "volume_group_list_cma_view?select=*&limit="+strconv.Itoa(c.limit))
 }
 
-func (c *Client) GetPerf(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_appliance",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetPerf(ctx context.Context, id, interval string) ([]AppliancePerformance, error) {
+	return generateMetrics[AppliancePerformance](ctx, c, EntityAppliancePerformance, id, interval)
 }
 
-func (c *Client) GetCap(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "space_metrics_by_appliance",
-		EntityID: id,
-		Interval: "One_Day",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetCap(ctx context.Context, id, interval string) ([]ApplianceSpace, error) {
+	return generateMetrics[ApplianceSpace](ctx, c, EntityApplianceSpace, id, interval)
 }
 
-func (c *Client) GetMetricVg(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_vg",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricVg(ctx context.Context, id, interval string) ([]VgPerformance, error) {
+	return generateMetrics[VgPerformance](ctx, c, EntityVgPerformance, id, interval)
 }
 
-func (c *Client) GetMetricVolume(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_volume",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricVolume(ctx context.Context, id, interval string) ([]VolumePerformance, error) {
+	return generateMetrics[VolumePerformance](ctx, c, EntityVolumePerformance, id, interval)
 }
 
-func (c *Client) GetMetricFcPort(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_fe_fc_port",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricFcPort(ctx context.Context, id, interval string) ([]FcPortPerformance, error) {
+	return generateMetrics[FcPortPerformance](ctx, c, EntityFcPortPerformance, id, interval)
 }
 
-func (c *Client) GetMetricEthPort(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_fe_eth_port",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricEthPort(ctx context.Context, id, interval string) ([]EthPortPerformance, error) {
+	return generateMetrics[EthPortPerformance](ctx, c, EntityEthPortPerformance, id, interval)
 }
 
-func (c *Client) GetMetricAppliance(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_appliance",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricAppliance(ctx context.Context, id, interval string) ([]AppliancePerformance, error) {
+	return generateMetrics[AppliancePerformance](ctx, c, EntityAppliancePerformance, id, interval)
 }
 
-func (c *Client) GetWearMetricByDrive(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "wear_metrics_by_drive",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricNode(ctx context.Context, id, interval string) ([]NodePerformance, error) {
+	return generateMetrics[NodePerformance](ctx, c, EntityNodePerformance, id, interval)
 }
 
-func (c *Client) GetMetricByNas(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_nas_server",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetWearMetricByDrive(ctx context.Context, id, interval string) ([]DriveWear, error) {
+	return generateMetrics[DriveWear](ctx, c, EntityDriveWear, id, interval)
 }
 
-func (c *Client) GetFilesystemCap(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "space_metrics_by_file_system",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetMetricByNas(ctx context.Context, id, interval string) ([]NasPerformance, error) {
+	return generateMetrics[NasPerformance](ctx, c, EntityNasPerformance, id, interval)
 }
 
-func (c *Client) GetMetricsFilesystem(id string) (string, error) {
-	var body = &RequestBody{
-		Entity:   "performance_metrics_by_file_system",
-		EntityID: id,
-		Interval: "Five_Mins",
-	}
-	entityBody, err := json.Marshal(body)
-	if err != nil {
-		return "", err
-	}
-	return c.getData("metrics/generate", "POST", string(entityBody))
+func (c *Client) GetFilesystemCap(ctx context.Context, id, interval string) ([]FilesystemSpace, error) {
+	return generateMetrics[FilesystemSpace](ctx, c, EntityFilesystemSpace, id, interval)
 }
 
-func (c *Client) GetApplianceId() (string, error) {
-	return c.getData("appliance?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetMetricsFilesystem(ctx context.Context, id, interval string) ([]FilesystemPerformance, error) {
+	return generateMetrics[FilesystemPerformance](ctx, c, EntityFilesystemPerformance, id, interval)
 }
 
-func (c *Client) GetVolumeGroupId() (string, error) {
-	return c.getData("volume_group_list_cma_view?select=id,name,appliance_ids&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetApplianceId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "appliance?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) GetVolumeId() (string, error) {
+func (c *Client) GetVolumeGroupId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "volume_group_list_cma_view?select=id,name,appliance_ids&limit="+strconv.Itoa(c.limit), "GET", "")
+}
+
+func (c *Client) GetVolumeId(ctx context.Context) (string, error) {
 	if c.version == "v3" {
-		return c.getData("volume_list_cma_view?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
+		return c.getData(ctx, "volume_list_cma_view?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
 	}
-	return c.getData("volume?select=id,name&type=eq.Drive&limit="+strconv.Itoa(c.limit), "GET", "")
+	return c.getData(ctx, "volume?select=id,name,appliance_id,type&type=eq.Drive&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) GetEthPortId() (string, error) {
-	return c.getData("eth_port?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetEthPortId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "eth_port?select=id,name,appliance_id,node_id&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) GetFcPortId() (string, error) {
-	return c.getData("fc_port?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetFcPortId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "fc_port?select=id,name,appliance_id,node_id&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) GetDrivesId() (string, error) {
-	return c.getData("hardware?select=id,name&type=eq.Drive&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetDrivesId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "hardware?select=id,name,appliance_id,type,parent_id&type=eq.Drive&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) GetNasId() (string, error) {
-	return c.getData("nas_server_list_cma_view?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetNasId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "nas_server_list_cma_view?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) GetFilesystemId() (string, error) {
-	return c.getData("file_system?select=id,name&limit="+strconv.Itoa(c.limit), "GET", "")
+func (c *Client) GetFilesystemId(ctx context.Context) (string, error) {
+	return c.getData(ctx, "file_system?select=id,name,nas_server_id&limit="+strconv.Itoa(c.limit), "GET", "")
 }
 
-func (c *Client) InitModuleID(logger log.Logger) {
-	ModuleIdToNameMap := make(map[string]map[string]gjson.Result)
-	applianceIdToName, err := c.GetApplianceId()
+// getList Returns the resources of a collection query decoded into T
+func getList[T any](ctx context.Context, c *Client, resource, path string) ([]T, error) {
+	data, err := c.getData(ctx, path, "GET", "")
 	if err != nil {
-		level.Error(logger).Log("msg", "Init appliance id list error", "err", err, "ip", c.IP)
+		return nil, err
 	}
-	ModuleIdToNameMap["appliance"] = resultToMap(applianceIdToName)
-
-	volumeIdToName, err := c.GetVolumeId()
-	if err != nil {
-		level.Error(logger).Log("msg", "Init volume id list error", "err", err, "ip", c.IP)
-	}
-	ModuleIdToNameMap["volume"] = resultToMap(volumeIdToName)
+	return decodeList[T](resource, data)
+}
 
-	volumeGroupIdToName, err := c.GetVolumeGroupId()
-	if err != nil {
-		level.Error(logger).Log("msg", "Init volume group id list error", "err", err, "ip", c.IP)
+// generateMetrics Returns the samples of the entity over the interval from metrics/generate decoded into T
+func generateMetrics[T any](ctx context.Context, c *Client, entity, id, interval string) ([]T, error) {
+	var body = &RequestBody{
+		Entity:   entity,
+		EntityID: id,
+		Interval: interval,
 	}
-	ModuleIdToNameMap["volumegroup"] = resultToMap(volumeGroupIdToName)
-
-	ethPortIdToName, err := c.GetEthPortId()
+	entityBody, err := json.Marshal(body)
 	if err != nil {
-		level.Error(logger).Log("msg", "Init eth port id list error", "err", err, "ip", c.IP)
+		return nil, err
 	}
-	ModuleIdToNameMap["ethport"] = resultToMap(ethPortIdToName)
-
-	fcPortIdToName, err := c.GetFcPortId()
+	data, err := c.getData(ctx, "metrics/generate", "POST", string(entityBody))
 	if err != nil {
-		level.Error(logger).Log("msg", "Init fc port id list error", "err", err, "ip", c.IP)
+		return nil, err
 	}
-	ModuleIdToNameMap["fcport"] = resultToMap(fcPortIdToName)
+	return decodeList[T](entity, data)
+}
 
-	drivesIdToName, err := c.GetDrivesId()
-	if err != nil {
-		level.Error(logger).Log("msg", "Init drives id list error", "err", err, "ip", c.IP)
-	}
-	ModuleIdToNameMap["drive"] = resultToMap(drivesIdToName)
+type priorityKey struct{}
 
-	nasIdToName, err := c.GetNasId()
-	if err != nil {
-		level.Error(logger).Log("msg", "Init nas server id list error", "err", err, "ip", c.IP)
-	}
-	ModuleIdToNameMap["nas"] = resultToMap(nasIdToName)
+// withPriority Returns a context whose requests are scheduled with the priority, e.g. the inventory loads
+func withPriority(ctx context.Context, priority utils.Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
 
-	filesystemIdToName, err := c.GetFilesystemId()
-	if err != nil {
-		level.Error(logger).Log("msg", "Init filesystem server id list error", "err", err, "ip", c.IP)
-	}
-	ModuleIdToNameMap["filesystem"] = resultToMap(filesystemIdToName)
-	PowerstoreModuleID[c.IP] = ModuleIdToNameMap
+// healthResources The resources of the status calls, scheduled before the collection queries
+var healthResources = map[string]bool{
+	"cluster":   true,
+	"hardware":  true,
+	"appliance": true,
 }
 
-// resultToMap Convert http response body to map structure
-func resultToMap(result string) map[string]gjson.Result {
-	var resultMap = make(map[string]gjson.Result)
-	for _, entity := range gjson.Parse(result).Array() {
-		resultMap[entity.Get("id").String()] = entity.Get("name")
+// priorityOf Returns the scheduling priority of a request, the metrics/generate fan-out comes last
+func priorityOf(ctx context.Context, path string) utils.Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(utils.Priority); ok {
+		return priority
+	}
+	resource := path
+	if i := strings.IndexByte(resource, '?'); i >= 0 {
+		resource = resource[:i]
+	}
+	switch {
+	case resource == "metrics/generate":
+		return utils.PriorityBulk
+	case healthResources[resource]:
+		return utils.PriorityHigh
 	}
-	return resultMap
+	return utils.PriorityNormal
 }
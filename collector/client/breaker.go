@@ -0,0 +1,184 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// breakerFailures The number of consecutive requests without response opening the breaker
+	breakerFailures      = 3
+	breakerProbeInterval = 30 * time.Second
+	breakerProbeTimeout  = 10 * time.Second
+)
+
+// ErrArrayUnreachable The request was not sent because the powerstore did not answer the last requests
+var ErrArrayUnreachable = errors.New("powerstore is unreachable, the request was not sent")
+
+var arrayReachable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "powerstore_array_reachable",
+	Help: "Whether the requests are sent to the powerstore,1 is reachable,0 is unreachable and only probed with login_session",
+}, []string{"IP"})
+
+func init() {
+	prometheus.MustRegister(arrayReachable)
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+var breakerStateNames = map[breakerState]string{
+	breakerClosed:   "closed",
+	breakerOpen:     "open",
+	breakerHalfOpen: "half-open",
+}
+
+// breaker The circuit breaker of a powerstore. It opens after consecutive requests got no response,
+// then requests fail fast while login_session is probed periodically, the probe being the half-open state.
+// The first probe answered by the powerstore closes it again.
+type breaker struct {
+	ip       string
+	probe    func(ctx context.Context) error
+	logger   log.Logger
+	lock     sync.Mutex
+	state    breakerState
+	failures int
+	// the period and timeout of the probes of an open breaker
+	probeInterval time.Duration
+	probeTimeout  time.Duration
+	// ctx is cancelled by stop, it ends the probes
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newBreaker(ip string, probe func(ctx context.Context) error, logger log.Logger) *breaker {
+	arrayReachable.WithLabelValues(ip).Set(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &breaker{ip: ip, probe: probe, logger: logger, probeInterval: breakerProbeInterval, probeTimeout: breakerProbeTimeout,
+		ctx: ctx, cancel: cancel}
+}
+
+// stop End the probes of the breaker, an open breaker is no longer probed and stays open
+func (b *breaker) stop() {
+	b.cancel()
+}
+
+// allow Returns ErrArrayUnreachable when the breaker is not closed
+func (b *breaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state != breakerClosed {
+		return ErrArrayUnreachable
+	}
+	return nil
+}
+
+// record Count the outcome of a request sent with ctx, any answer of the powerstore, even an error status, is a success.
+// The requests given up by the caller are not counted.
+func (b *breaker) record(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !unreachable(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= breakerFailures {
+		b.setState(breakerOpen, err)
+		if b.ctx.Err() == nil {
+			go b.probeLoop()
+		}
+	}
+}
+
+// probeLoop Probe the powerstore every probeInterval until it answers or the breaker is stopped
+func (b *breaker) probeLoop() {
+	ticker := time.NewTicker(b.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.lock.Lock()
+		b.setState(breakerHalfOpen, nil)
+		b.lock.Unlock()
+		ctx, cancel := context.WithTimeout(b.ctx, b.probeTimeout)
+		err := b.probe(ctx)
+		cancel()
+		b.lock.Lock()
+		if b.ctx.Err() != nil {
+			// the probe was interrupted by stop, it tells nothing of the powerstore
+			b.setState(breakerOpen, nil)
+			b.lock.Unlock()
+			return
+		}
+		if !unreachable(err) {
+			b.failures = 0
+			b.setState(breakerClosed, nil)
+			b.lock.Unlock()
+			return
+		}
+		b.setState(breakerOpen, err)
+		b.lock.Unlock()
+	}
+}
+
+// setState Change the state, the lock must be held
+func (b *breaker) setState(state breakerState, err error) {
+	if b.state == state {
+		return
+	}
+	previous := b.state
+	b.state = state
+	switch state {
+	case breakerClosed:
+		arrayReachable.WithLabelValues(b.ip).Set(1)
+		level.Info(b.logger).Log("msg", "the powerstore is reachable again", "ip", b.ip)
+	case breakerOpen:
+		arrayReachable.WithLabelValues(b.ip).Set(0)
+		if previous == breakerClosed {
+			level.Error(b.logger).Log("msg", "the powerstore is unreachable, requests fail fast until login_session answers", "ip", b.ip, "err", err)
+		}
+	}
+	level.Debug(b.logger).Log("msg", "circuit breaker state changed", "ip", b.ip, "from", breakerStateNames[previous], "to", breakerStateNames[state])
+}
+
+// unreachable Whether the request got no response from the powerstore, e.g. a dial timeout or a refused connection
+func unreachable(err error) bool {
+	var urlErr *url.Error
+	var handshakeErr *HandshakeError
+	return errors.As(err, &urlErr) && !errors.As(err, &handshakeErr) && !errors.Is(err, context.Canceled)
+}
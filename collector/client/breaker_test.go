@@ -0,0 +1,189 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// dialError An error of a request that got no response from the powerstore
+var dialError = &url.Error{Op: "Post", URL: "https://10.0.0.1/api/rest/metrics/generate", Err: errors.New("connection refused")}
+
+// reachable The value of powerstore_array_reachable of the ip
+func reachable(ip string) float64 {
+	return testutil.ToFloat64(arrayReachable.WithLabelValues(ip))
+}
+
+// waitClosed Wait until the probes close the breaker
+func waitClosed(t *testing.T, b *breaker) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for b.allow() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the breaker was not closed by the probes")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBreakerOpens(t *testing.T) {
+	// the probes keep failing so that the breaker stays open
+	b := newBreaker("breaker-opens", func(ctx context.Context) error { return dialError }, log.NewNopLogger())
+	defer b.stop()
+	if reachable(b.ip) != 1 {
+		t.Fatalf("powerstore_array_reachable = %v for a new breaker, want 1", reachable(b.ip))
+	}
+	for i := 1; i < breakerFailures; i++ {
+		b.record(context.Background(), dialError)
+		if err := b.allow(); err != nil {
+			t.Fatalf("breaker open after %d failures, want %d", i, breakerFailures)
+		}
+	}
+	b.record(context.Background(), dialError)
+	if err := b.allow(); !errors.Is(err, ErrArrayUnreachable) {
+		t.Fatalf("allow() = %v after %d failures, want ErrArrayUnreachable", err, breakerFailures)
+	}
+	if reachable(b.ip) != 0 {
+		t.Errorf("powerstore_array_reachable = %v for an open breaker, want 0", reachable(b.ip))
+	}
+}
+
+func TestBreakerIgnoresAnswers(t *testing.T) {
+	b := newBreaker("breaker-answers", func(ctx context.Context) error { return nil }, log.NewNopLogger())
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{"error status", context.Background(), errors.New("the powerstore answered 500")},
+		{"handshake", context.Background(), &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: &HandshakeError{Err: errors.New("bad certificate")}}},
+		{"cancelled request", context.Background(), &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: context.Canceled}},
+		{"abandoned scrape", cancelled, dialError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 2*breakerFailures; i++ {
+				b.record(test.ctx, test.err)
+			}
+			if err := b.allow(); err != nil {
+				t.Errorf("allow() = %v, want the breaker closed", err)
+			}
+		})
+	}
+
+	// an answer resets the count of consecutive failures
+	for i := 0; i < 2*breakerFailures; i++ {
+		b.record(context.Background(), dialError)
+		if i%2 == 0 {
+			continue
+		}
+		b.record(context.Background(), nil)
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("allow() = %v after failures separated by answers, want the breaker closed", err)
+	}
+}
+
+func TestBreakerProbeCloses(t *testing.T) {
+	var probes int32
+	b := newBreaker("breaker-probe", func(ctx context.Context) error {
+		// the powerstore answers the third probe
+		if atomic.AddInt32(&probes, 1) < 3 {
+			return dialError
+		}
+		return nil
+	}, log.NewNopLogger())
+	b.probeInterval = time.Millisecond
+	for i := 0; i < breakerFailures; i++ {
+		b.record(context.Background(), dialError)
+	}
+	if b.allow() == nil {
+		t.Fatal("breaker closed after the failures, want open")
+	}
+	waitClosed(t, b)
+	if got := atomic.LoadInt32(&probes); got != 3 {
+		t.Errorf("breaker closed after %d probes, want 3", got)
+	}
+	if reachable(b.ip) != 1 {
+		t.Errorf("powerstore_array_reachable = %v once closed, want 1", reachable(b.ip))
+	}
+
+	// the count starts over once closed
+	for i := 1; i < breakerFailures; i++ {
+		b.record(context.Background(), dialError)
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("allow() = %v, the failures before the probes must not count", err)
+	}
+}
+
+func TestBreakerStop(t *testing.T) {
+	var probes int32
+	probing := make(chan struct{})
+	interrupted := make(chan struct{})
+	b := newBreaker("breaker-stop", func(ctx context.Context) error {
+		// the first probe hangs until the breaker is stopped
+		if atomic.AddInt32(&probes, 1) == 1 {
+			close(probing)
+			<-ctx.Done()
+			close(interrupted)
+		}
+		return dialError
+	}, log.NewNopLogger())
+	b.probeInterval = time.Millisecond
+	for i := 0; i < breakerFailures; i++ {
+		b.record(context.Background(), dialError)
+	}
+	<-probing
+	b.stop()
+	select {
+	case <-interrupted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the probe in flight was not cancelled by stop")
+	}
+	time.Sleep(20 * b.probeInterval)
+	if got := atomic.LoadInt32(&probes); got != 1 {
+		t.Errorf("%d probes sent, want no probe after stop", got)
+	}
+	if err := b.allow(); !errors.Is(err, ErrArrayUnreachable) {
+		t.Errorf("allow() = %v after stop, want the breaker to stay open", err)
+	}
+}
+
+func TestLogoutStopsBreaker(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < breakerFailures; i++ {
+		c.breaker.record(context.Background(), dialError)
+	}
+	if err := c.Logout(context.Background()); err != nil {
+		t.Fatalf("Logout(): %v", err)
+	}
+	if c.breaker.ctx.Err() == nil {
+		t.Error("the probes of the breaker still run after Logout")
+	}
+}
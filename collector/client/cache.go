@@ -0,0 +1,141 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "powerstore_api_cache_requests_total",
+	Help: "Number of powerstore rest api calls by result,hit is answered from the cache,shared waited for the same call in flight,miss was sent",
+}, []string{"IP", "result"})
+
+func init() {
+	prometheus.MustRegister(cacheRequests)
+}
+
+// responseCache Shares the calls of a powerstore: the calls in flight with the same path and body are sent once,
+// and the responses are kept for ttl, so that overlapping scrapes do not query the powerstore twice
+type responseCache struct {
+	ip        string
+	ttl       time.Duration
+	lock      sync.Mutex
+	entries   map[string]cacheEntry
+	calls     map[string]*sharedCall
+	nextPrune time.Time
+}
+
+type cacheEntry struct {
+	result  string
+	expires time.Time
+}
+
+// sharedCall A call in flight, cancelled when every caller waiting for it has given up
+type sharedCall struct {
+	done    chan struct{}
+	result  string
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newResponseCache(ip string, ttl time.Duration) *responseCache {
+	return &responseCache{
+		ip:      ip,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+		calls:   make(map[string]*sharedCall),
+	}
+}
+
+// do Returns the cached response of the key, or the response of the call in flight for the key, or calls fetch
+func (r *responseCache) do(ctx context.Context, key string, fetch func(ctx context.Context) (string, error)) (string, error) {
+	r.lock.Lock()
+	if entry, ok := r.entries[key]; ok && time.Now().Before(entry.expires) {
+		r.lock.Unlock()
+		cacheRequests.WithLabelValues(r.ip, "hit").Inc()
+		return entry.result, nil
+	}
+	call, ok := r.calls[key]
+	if ok {
+		call.waiters++
+		cacheRequests.WithLabelValues(r.ip, "shared").Inc()
+	} else {
+		// the call outlives the caller that started it as long as other callers wait for it
+		callCtx, cancel := context.WithCancel(context.Background())
+		if priority := ctx.Value(priorityKey{}); priority != nil {
+			callCtx = context.WithValue(callCtx, priorityKey{}, priority)
+		}
+		call = &sharedCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		r.calls[key] = call
+		cacheRequests.WithLabelValues(r.ip, "miss").Inc()
+		go r.run(callCtx, key, call, fetch)
+	}
+	r.lock.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			// the next caller must not wait for the cancelled call
+			if r.calls[key] == call {
+				delete(r.calls, key)
+			}
+		}
+		return "", ctx.Err()
+	}
+}
+
+func (r *responseCache) run(ctx context.Context, key string, call *sharedCall, fetch func(ctx context.Context) (string, error)) {
+	result, err := fetch(ctx)
+	call.cancel()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.calls[key] == call {
+		delete(r.calls, key)
+	}
+	if err == nil && r.ttl > 0 {
+		now := time.Now()
+		r.prune(now)
+		r.entries[key] = cacheEntry{result: result, expires: now.Add(r.ttl)}
+	}
+	call.result, call.err = result, err
+	close(call.done)
+}
+
+// prune Drop the expired responses once per ttl, the lock must be held
+func (r *responseCache) prune(now time.Time) {
+	if now.Before(r.nextPrune) {
+		return
+	}
+	for key, entry := range r.entries {
+		if !now.Before(entry.expires) {
+			delete(r.entries, key)
+		}
+	}
+	r.nextPrune = now.Add(r.ttl)
+}
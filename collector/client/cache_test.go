@@ -0,0 +1,166 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitWaiters Wait until n callers wait for the call in flight of the key
+func waitWaiters(t *testing.T, r *responseCache, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.lock.Lock()
+		waiters := 0
+		if call, ok := r.calls[key]; ok {
+			waiters = call.waiters
+		}
+		r.lock.Unlock()
+		if waiters == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers wait for %s, want %d", waiters, key, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheSharesCalls(t *testing.T) {
+	r := newResponseCache("cache-shared", 0)
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return "result", nil
+	}
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, err := r.do(context.Background(), "volume", fetch); result != "result" || err != nil {
+				t.Errorf("do() = %q, %v, want the shared result", result, err)
+			}
+		}()
+		waitWaiters(t, r, "volume", i)
+	}
+	close(release)
+	wg.Wait()
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Errorf("%d calls sent for 3 concurrent callers, want 1", atomic.LoadInt32(&fetches))
+	}
+
+	// without ttl the response is not kept once the call is done
+	release = make(chan struct{})
+	close(release)
+	r.do(context.Background(), "volume", fetch)
+	if atomic.LoadInt32(&fetches) != 2 {
+		t.Errorf("%d calls sent, want a new call once the shared one is done", atomic.LoadInt32(&fetches))
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	r := newResponseCache("cache-ttl", 50*time.Millisecond)
+	var fetches int32
+	fetch := func(ctx context.Context) (string, error) {
+		return "result", nil
+	}
+	counted := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&fetches, 1)
+		return fetch(ctx)
+	}
+	for i := 0; i < 3; i++ {
+		if result, err := r.do(context.Background(), "volume", counted); result != "result" || err != nil {
+			t.Fatalf("do() = %q, %v", result, err)
+		}
+	}
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Errorf("%d calls sent within the ttl, want 1", atomic.LoadInt32(&fetches))
+	}
+	time.Sleep(60 * time.Millisecond)
+	r.do(context.Background(), "volume", counted)
+	if atomic.LoadInt32(&fetches) != 2 {
+		t.Errorf("%d calls sent, want the expired response fetched again", atomic.LoadInt32(&fetches))
+	}
+
+	// the errors are not cached
+	failed := errors.New("failed")
+	r.do(context.Background(), "cluster", func(ctx context.Context) (string, error) { return "", failed })
+	if _, err := r.do(context.Background(), "cluster", fetch); err != nil {
+		t.Errorf("do() = %v, the error of the previous call was cached", err)
+	}
+}
+
+func TestCacheCancelsAbandonedCall(t *testing.T) {
+	r := newResponseCache("cache-cancel", time.Minute)
+	cancelled := make(chan struct{})
+	fetch := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return "", ctx.Err()
+	}
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := r.do(first, "volume", fetch)
+		errs <- err
+	}()
+	waitWaiters(t, r, "volume", 1)
+	go func() {
+		_, err := r.do(second, "volume", fetch)
+		errs <- err
+	}()
+	waitWaiters(t, r, "volume", 2)
+
+	// the call outlives the caller that started it
+	cancelFirst()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("do() = %v for the cancelled caller, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("the call was cancelled while a caller still waits for it")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("do() = %v for the last caller, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call was not cancelled when the last caller left")
+	}
+
+	// the next caller does not wait for the cancelled call
+	result, err := r.do(context.Background(), "volume", func(ctx context.Context) (string, error) {
+		return "result", nil
+	})
+	if result != "result" || err != nil {
+		t.Errorf("do() = %q, %v after the cancellation, want a new call", result, err)
+	}
+}
@@ -18,12 +18,15 @@ package client
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"powerstore-metrics-exporter/utils"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -31,16 +34,21 @@ import (
 )
 
 type Client struct {
-	IP       string
-	username string
-	password string
-	version  string
-	limit    int
-	baseUrl  string
-	http     *http.Client
-	token    string
-	cookie   string
-	logger   log.Logger
+	IP        string
+	username  string
+	password  string
+	version   string
+	limit     int
+	baseUrl   string
+	http      *http.Client
+	session   session
+	loginLock sync.Mutex
+	logger    log.Logger
+	inventory *Inventory
+	retry     retryPolicy
+	breaker   *breaker
+	generate  *aimdLimiter
+	cache     *responseCache
 }
 
 func NewClient(config utils.Storage, logger log.Logger) (*Client, error) {
@@ -54,39 +62,60 @@ func NewClient(config utils.Storage, logger log.Logger) (*Client, error) {
 		limit = config.Limit
 	}
 	baseUrl := "https://" + config.Ip + "/api/rest/"
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig.InsecureSkipVerify && config.TLS.Fingerprint == "" {
+		level.Warn(logger).Log("msg", "the certificate of the powerstore is not verified", "ip", config.Ip)
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport, err := newTrafficTransport(config.Traffic, config.Ip, &http.Transport{
+		DialContext:           dialer.DialContext,
+		DialTLSContext:        dialTLS(config.Ip, dialer, tlsConfig),
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
 	var httpClient *http.Client
 	httpClient = &http.Client{
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
-		Timeout: 60 * time.Second,
+		Transport: transport,
+		Timeout:   60 * time.Second,
 	}
 	client := &Client{
-		IP:       config.Ip,
-		username: config.User,
-		password: config.Password,
-		version:  config.Version,
-		limit:    limit,
-		baseUrl:  baseUrl,
-		http:     httpClient,
-		logger:   logger,
+		IP:        config.Ip,
+		username:  config.User,
+		password:  config.Password,
+		version:   config.Version,
+		limit:     limit,
+		baseUrl:   baseUrl,
+		http:      httpClient,
+		logger:    logger,
+		inventory: NewInventory(),
+		retry:     newRetryPolicy(config.Retry),
+		generate:  newAimdLimiter(config.Ip),
+		cache:     newResponseCache(config.Ip, time.Duration(config.CacheTTL*float64(time.Second))),
 	}
+	client.breaker = newBreaker(config.Ip, client.login, logger)
+	utils.RequestScheduler.SetArrayLimit(config.Ip, config.ReqLimit)
 	return client, client.InitLogin()
 }
 
 func (c *Client) InitLogin() error {
+	return c.login(context.Background())
+}
+
+// login Get a new authentication token and cookie from login_session
+func (c *Client) login(ctx context.Context) error {
 	reqUrl := c.baseUrl + "login_session"
-	request, err := http.NewRequest("GET", reqUrl, bytes.NewBuffer([]byte("")))
+	request, err := http.NewRequestWithContext(ctx, "GET", reqUrl, bytes.NewBuffer([]byte("")))
 	if err != nil {
 		return err
 	}
@@ -95,19 +124,20 @@ func (c *Client) InitLogin() error {
 	request.Header.Set("Content-Type", "application/json")
 	response, err := c.http.Do(request)
 	if err != nil {
-		level.Warn(c.logger).Log("msg", "Request URL error!")
-		return err
+		return c.requestError(err)
 	}
 	defer response.Body.Close()
 	switch response.StatusCode {
 	case http.StatusOK, http.StatusCreated:
-		c.token = response.Header.Get("Dell-Emc-Token")
+		token := response.Header.Get("Dell-Emc-Token")
+		var authCookie string
 		cookies := response.Cookies()
 		for _, cookie := range cookies {
 			if cookie.Name == "auth_cookie" {
-				c.cookie = cookie.Value
+				authCookie = cookie.Value
 			}
 		}
+		c.session.set(token, authCookie)
 		return nil
 	default:
 		body, err := io.ReadAll(response.Body)
@@ -116,49 +146,155 @@ func (c *Client) InitLogin() error {
 	}
 }
 
-func (c *Client) getResource(method, uri, body string) (string, error) {
+// getResource Returns the response body and its Content-Range header, which is set when a collection is returned in pages.
+// The idempotent requests failed with a transient error are sent again after a backoff.
+func (c *Client) getResource(ctx context.Context, method, uri, body string) (string, string, error) {
+	for attempt := 1; ; attempt++ {
+		if err := c.breaker.allow(); err != nil {
+			return "", "", err
+		}
+		result, contentRange, err := c.sendAttempt(ctx, method, uri, body)
+		c.breaker.record(ctx, err)
+		if err == nil || !idempotent(method, uri) {
+			return result, contentRange, err
+		}
+		delay, ok := c.retry.delay(attempt, err)
+		if !ok {
+			return result, contentRange, err
+		}
+		endpoint := endpointOf(uri, body)
+		level.Debug(c.logger).Log("msg", "retry powerstore request", "ip", c.IP, "endpoint", endpoint, "attempt", attempt, "delay", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", "", ctx.Err()
+		}
+		apiRetries.WithLabelValues(c.IP, method, endpoint).Inc()
+	}
+}
+
+// sendAttempt Send one attempt of the request in a slot of the scheduler, the slot is free during the backoff
+// before the next attempt so that the other requests to the powerstore are not held up by a throttled endpoint.
+// The metrics/generate attempts are also limited by the adaptive limiter, which is given the round trip of the
+// request only, not the time spent waiting for a slot.
+func (c *Client) sendAttempt(ctx context.Context, method, uri, body string) (result, contentRange string, err error) {
+	var roundTrip time.Duration
+	if uri == "metrics/generate" {
+		release, acquireErr := c.generate.acquire(ctx)
+		if acquireErr != nil {
+			return "", "", acquireErr
+		}
+		defer func() {
+			release(roundTrip, err)
+		}()
+	}
+	free, err := utils.RequestScheduler.Acquire(ctx, c.IP, priorityOf(ctx, uri))
+	if err != nil {
+		return "", "", err
+	}
+	defer free()
+	result, contentRange, roundTrip, err = c.sendResource(ctx, method, uri, body)
+	return result, contentRange, err
+}
+
+// sendResource Send the request, and again after a login when the token has expired,
+// the round trip of the last request is returned
+func (c *Client) sendResource(ctx context.Context, method, uri, body string) (string, string, time.Duration, error) {
+	for relogins := 0; ; relogins++ {
+		token, cookie, generation := c.session.get()
+		result, contentRange, roundTrip, err := c.sendRequest(ctx, method, uri, body, token, cookie)
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.Code != http.StatusUnauthorized && statusErr.Code != http.StatusFound {
+			return result, contentRange, roundTrip, err
+		}
+		if relogins >= maxRelogins {
+			level.Warn(c.logger).Log("msg", "authentication token is still invalid after relogin", "ip", c.IP, "err", err)
+			return "", "", roundTrip, err
+		}
+		level.Warn(c.logger).Log("msg", "authentication token is invalid, relogin...", "ip", c.IP)
+		if err := c.relogin(ctx, generation); err != nil {
+			level.Warn(c.logger).Log("msg", "init auth error", "err", err)
+			return "", "", roundTrip, err
+		}
+	}
+}
+
+// relogin Login again unless another request did it since the session generation was read,
+// so that the requests rejected at the same time share one login
+func (c *Client) relogin(ctx context.Context, generation uint64) error {
+	c.loginLock.Lock()
+	defer c.loginLock.Unlock()
+	if _, _, current := c.session.get(); current != generation {
+		return nil
+	}
+	apiRelogins.WithLabelValues(c.IP).Inc()
+	return c.login(ctx)
+}
+
+// sendRequest Send the request once with the session token and cookie, the round trip to the powerstore is returned with the response
+func (c *Client) sendRequest(ctx context.Context, method, uri, body, token, cookie string) (string, string, time.Duration, error) {
+	endpoint := endpointOf(uri, body)
 	reqUrl := c.baseUrl + uri
-	request, err := http.NewRequest(method, reqUrl, bytes.NewBuffer([]byte(body)))
+	request, err := http.NewRequestWithContext(ctx, method, reqUrl, bytes.NewBuffer([]byte(body)))
 	if err != nil {
-		return "", err
+		return "", "", 0, err
 	}
 	request.Header.Set("Accept", "application/json")
 	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("DELL-EMC-TOKEN", c.token)
-	request.Header.Set("Cookie", "auth_cookie="+c.cookie)
+	request.Header.Set("DELL-EMC-TOKEN", token)
+	request.Header.Set("Cookie", "auth_cookie="+cookie)
 
 	// Added parameters in Powerstore API 4.1.0
 	request.Header.Set("dell-visibility", "Internal")
 
+	startTime := time.Now()
 	response, err := c.http.Do(request)
 	if err != nil {
-		level.Warn(c.logger).Log("msg", "Request URL error!")
-		return "", err
+		roundTrip := time.Since(startTime)
+		apiRequests.WithLabelValues(c.IP, method, endpoint, "error").Inc()
+		apiRequestDuration.WithLabelValues(c.IP, method, endpoint).Observe(roundTrip.Seconds())
+		return "", "", roundTrip, c.requestError(err)
 	}
 
 	defer response.Body.Close()
+	respBody, err := io.ReadAll(response.Body)
+	roundTrip := time.Since(startTime)
+	apiRequests.WithLabelValues(c.IP, method, endpoint, strconv.Itoa(response.StatusCode)).Inc()
+	apiRequestDuration.WithLabelValues(c.IP, method, endpoint).Observe(roundTrip.Seconds())
+	apiResponseSize.WithLabelValues(c.IP, method, endpoint).Observe(float64(len(respBody)))
 	switch response.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusPartialContent:
-		body, err := io.ReadAll(response.Body)
-		if err != nil {
-			return "", errors.New("get resource error: " + string(body))
-		}
-		return string(body), nil
-	case http.StatusUnauthorized, http.StatusFound:
-		level.Warn(c.logger).Log("msg", "authentication token is invalid, relogin...", "err", err)
-		err = c.InitLogin()
 		if err != nil {
-			level.Warn(c.logger).Log("msg", "init auth error", "err", err)
-			return "", err
-		} else {
-			return c.getResource(method, uri, body)
+			return "", "", roundTrip, fmt.Errorf("get resource error: %s: %w", respBody, err)
 		}
+		return string(respBody), response.Header.Get("Content-Range"), roundTrip, nil
 	default:
-		body, err := io.ReadAll(response.Body)
+		statusErr := &StatusError{
+			Code:       response.StatusCode,
+			RetryAfter: retryAfter(response.Header.Get("Retry-After")),
+			message:    "get resource error ReadAll err is nil: " + string(respBody),
+		}
 		if err != nil {
-			return "", errors.New("get resource error ReadAll err is not nil: " + string(body))
+			statusErr.message = "get resource error ReadAll err is not nil: " + string(respBody)
 		}
-		return "", errors.New("get resource error ReadAll err is nil: " + string(body))
+		return "", "", roundTrip, statusErr
 	}
 
 }
+
+// requestError Log the error of a request that got no response, a failed TLS handshake is returned as a HandshakeError
+func (c *Client) requestError(err error) error {
+	var handshakeErr *HandshakeError
+	if errors.As(err, &handshakeErr) {
+		level.Error(c.logger).Log("msg", "TLS handshake with the powerstore failed, check the tls config of the storage", "ip", c.IP, "err", handshakeErr.Err)
+		return handshakeErr
+	}
+	// the scrape was abandoned, the collectors report it
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	level.Warn(c.logger).Log("msg", "Request URL error!")
+	return err
+}
@@ -0,0 +1,225 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"powerstore-metrics-exporter/utils"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// The module types stored in the inventory
+const (
+	ModuleAppliance   = "appliance"
+	ModuleVolume      = "volume"
+	ModuleVolumeGroup = "volumegroup"
+	ModuleEthPort     = "ethport"
+	ModuleFcPort      = "fcport"
+	ModuleDrive       = "drive"
+	ModuleNas         = "nas"
+	ModuleFilesystem  = "filesystem"
+)
+
+// Entry One object of the powerstore inventory
+type Entry struct {
+	ID          string
+	Name        string
+	ApplianceID string
+	// Type The powerstore type of the object, e.g. Primary for a volume or Drive for a hardware
+	Type string
+	// Parent The id of the object this one belongs to, e.g. the nas server of a filesystem or the node of a port
+	Parent string
+}
+
+// Inventory The objects of a powerstore grouped by module type and keyed by id, safe for concurrent use.
+// The objects of a module type are only ever replaced as a whole, so the maps handed out by Get can be read without holding the lock.
+type Inventory struct {
+	lock    sync.RWMutex
+	modules map[string]map[string]Entry
+}
+
+func NewInventory() *Inventory {
+	return &Inventory{modules: make(map[string]map[string]Entry)}
+}
+
+// Get Returns the objects of the module type keyed by id, the returned map must not be modified
+func (i *Inventory) Get(module string) map[string]Entry {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.modules[module]
+}
+
+// Lookup Returns the object of the module type with the id
+func (i *Inventory) Lookup(module, id string) (Entry, bool) {
+	entry, ok := i.Get(module)[id]
+	return entry, ok
+}
+
+// Replace Swap in the objects of all module types at once and return the previous ones
+func (i *Inventory) Replace(modules map[string]map[string]Entry) map[string]map[string]Entry {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	previous := i.modules
+	i.modules = modules
+	return previous
+}
+
+// inventoryLoaders The module types stored in the inventory, the api used to load their id list and the field holding their parent id
+var inventoryLoaders = []struct {
+	module string
+	name   string
+	parent string
+	load   func(c *Client, ctx context.Context) (string, error)
+}{
+	{ModuleAppliance, "appliance", "", (*Client).GetApplianceId},
+	{ModuleVolume, "volume", "", (*Client).GetVolumeId},
+	{ModuleVolumeGroup, "volume group", "", (*Client).GetVolumeGroupId},
+	{ModuleEthPort, "eth port", "node_id", (*Client).GetEthPortId},
+	{ModuleFcPort, "fc port", "node_id", (*Client).GetFcPortId},
+	{ModuleDrive, "drives", "parent_id", (*Client).GetDrivesId},
+	{ModuleNas, "nas server", "", (*Client).GetNasId},
+	{ModuleFilesystem, "filesystem", "nas_server_id", (*Client).GetFilesystemId},
+}
+
+var (
+	inventoryRefreshTime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "powerstore_inventory_last_refresh_timestamp_seconds",
+		Help: "Unix time of the last inventory refresh of the powerstore",
+	}, []string{"IP"})
+	inventoryObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "powerstore_inventory_objects",
+		Help: "Number of objects of each module type in the powerstore inventory",
+	}, []string{"IP", "type"})
+	inventoryChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "powerstore_inventory_changes_total",
+		Help: "Number of objects added to or removed from the powerstore inventory by refreshes",
+	}, []string{"IP", "type", "change"})
+)
+
+func init() {
+	prometheus.MustRegister(inventoryRefreshTime, inventoryObjects, inventoryChanges)
+}
+
+// Inventory Returns the objects of the powerstore loaded by InitModuleID
+func (c *Client) Inventory() *Inventory {
+	return c.inventory
+}
+
+// InitModuleID Load the objects of every module type of the powerstore into the inventory
+func (c *Client) InitModuleID(ctx context.Context, logger log.Logger) {
+	modules := make(map[string]map[string]Entry)
+	ctx = withPriority(ctx, utils.PriorityHigh)
+	for _, loader := range inventoryLoaders {
+		result, err := loader.load(c, ctx)
+		if err != nil {
+			level.Error(logger).Log("msg", "Init "+loader.name+" id list error", "err", err, "ip", c.IP)
+			// keep the last known objects instead of dropping them until the next refresh
+			if old := c.inventory.Get(loader.module); old != nil {
+				modules[loader.module] = old
+				continue
+			}
+		}
+		modules[loader.module] = resultToEntries(result, loader.module, loader.parent)
+	}
+	// stopped on shutdown, the partial result is dropped
+	if ctx.Err() != nil {
+		return
+	}
+	previous := c.inventory.Replace(modules)
+	recordInventory(c.IP, previous, modules, logger)
+}
+
+// RefreshModuleID Reload the module id map of the powerstore every interval, so that objects created or deleted after startup are picked up,
+// until ctx is cancelled
+func (c *Client) RefreshModuleID(ctx context.Context, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		startTime := time.Now()
+		c.InitModuleID(ctx, logger)
+		if ctx.Err() == nil {
+			level.Info(logger).Log("msg", "Refreshing the module id list is successful", "ip", c.IP, "time", time.Since(startTime))
+		}
+	}
+}
+
+// resultToEntries Convert http response body to inventory entries keyed by id
+func resultToEntries(result, module, parent string) map[string]Entry {
+	entries := make(map[string]Entry)
+	for _, entity := range gjson.Parse(result).Array() {
+		entry := Entry{
+			ID:          entity.Get("id").String(),
+			Name:        entity.Get("name").String(),
+			ApplianceID: entity.Get("appliance_id").String(),
+			Type:        entity.Get("type").String(),
+		}
+		switch {
+		case module == ModuleAppliance:
+			entry.ApplianceID = entry.ID
+		case entity.Get("appliance_ids").IsArray():
+			entry.ApplianceID = entity.Get("appliance_ids.0").String()
+		}
+		if entry.Type == "" {
+			entry.Type = module
+		}
+		if parent != "" {
+			entry.Parent = entity.Get(parent).String()
+		}
+		entries[entry.ID] = entry
+	}
+	return entries
+}
+
+// recordInventory Log the objects added and removed since the previous inventory and update the inventory metrics
+func recordInventory(ip string, previous, current map[string]map[string]Entry, logger log.Logger) {
+	inventoryRefreshTime.WithLabelValues(ip).SetToCurrentTime()
+	for module, objects := range current {
+		inventoryObjects.WithLabelValues(ip, module).Set(float64(len(objects)))
+		// the first load is not a change
+		if len(previous) == 0 {
+			continue
+		}
+		var added, removed int
+		for id := range objects {
+			if _, ok := previous[module][id]; !ok {
+				added++
+			}
+		}
+		for id := range previous[module] {
+			if _, ok := objects[id]; !ok {
+				removed++
+			}
+		}
+		if added == 0 && removed == 0 {
+			continue
+		}
+		inventoryChanges.WithLabelValues(ip, module, "added").Add(float64(added))
+		inventoryChanges.WithLabelValues(ip, module, "removed").Add(float64(removed))
+		level.Info(logger).Log("msg", "module id list changed", "ip", ip, "type", module, "added", added, "removed", removed)
+	}
+}
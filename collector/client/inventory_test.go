@@ -0,0 +1,163 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// changes The values of powerstore_inventory_changes_total of the module type
+func changes(ip, module string) (added, removed float64) {
+	return testutil.ToFloat64(inventoryChanges.WithLabelValues(ip, module, "added")),
+		testutil.ToFloat64(inventoryChanges.WithLabelValues(ip, module, "removed"))
+}
+
+func TestResultToEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		module string
+		parent string
+		want   map[string]Entry
+	}{
+		{"appliance is its own appliance", `[{"id":"A1","name":"appliance-1"}]`, ModuleAppliance, "",
+			map[string]Entry{"A1": {ID: "A1", Name: "appliance-1", ApplianceID: "A1", Type: ModuleAppliance}}},
+		{"appliance id", `[{"id":"p1","name":"BaseEnclosure-NodeA-IOModule0-FEPort0","appliance_id":"A1","node_id":"N1"}]`, ModuleFcPort, "node_id",
+			map[string]Entry{"p1": {ID: "p1", Name: "BaseEnclosure-NodeA-IOModule0-FEPort0", ApplianceID: "A1", Type: ModuleFcPort, Parent: "N1"}}},
+		{"first of appliance ids", `[{"id":"vg1","name":"group","appliance_ids":["A2","A1"]},{"id":"vg2","name":"empty","appliance_ids":[]}]`, ModuleVolumeGroup, "",
+			map[string]Entry{
+				"vg1": {ID: "vg1", Name: "group", ApplianceID: "A2", Type: ModuleVolumeGroup},
+				"vg2": {ID: "vg2", Name: "empty", Type: ModuleVolumeGroup},
+			}},
+		{"type and parent of a drive", `[{"id":"d1","name":"Drive_0","appliance_id":"A1","type":"Drive","parent_id":"enc1"}]`, ModuleDrive, "parent_id",
+			map[string]Entry{"d1": {ID: "d1", Name: "Drive_0", ApplianceID: "A1", Type: "Drive", Parent: "enc1"}}},
+		{"parent field missing", `[{"id":"fs1","name":"fs"}]`, ModuleFilesystem, "nas_server_id",
+			map[string]Entry{"fs1": {ID: "fs1", Name: "fs", Type: ModuleFilesystem}}},
+		{"empty list", `[]`, ModuleVolume, "", map[string]Entry{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := resultToEntries(test.result, test.module, test.parent); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resultToEntries() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecordInventory(t *testing.T) {
+	ip := "inventory-record"
+	first := map[string]map[string]Entry{
+		ModuleVolume: {"v1": {ID: "v1"}, "v2": {ID: "v2"}},
+		ModuleNas:    {"n1": {ID: "n1"}},
+	}
+	recordInventory(ip, nil, first, log.NewNopLogger())
+	// the first load is not a change
+	if added, removed := changes(ip, ModuleVolume); added != 0 || removed != 0 {
+		t.Errorf("volume changes = %v added, %v removed after the first load, want none", added, removed)
+	}
+	if got := testutil.ToFloat64(inventoryObjects.WithLabelValues(ip, ModuleVolume)); got != 2 {
+		t.Errorf("powerstore_inventory_objects = %v volumes, want 2", got)
+	}
+
+	second := map[string]map[string]Entry{
+		ModuleVolume: {"v2": {ID: "v2"}, "v3": {ID: "v3"}, "v4": {ID: "v4"}},
+		ModuleNas:    {"n1": {ID: "n1"}},
+	}
+	recordInventory(ip, first, second, log.NewNopLogger())
+	if added, removed := changes(ip, ModuleVolume); added != 2 || removed != 1 {
+		t.Errorf("volume changes = %v added, %v removed, want 2 added, 1 removed", added, removed)
+	}
+	if added, removed := changes(ip, ModuleNas); added != 0 || removed != 0 {
+		t.Errorf("nas changes = %v added, %v removed for an unchanged list, want none", added, removed)
+	}
+	if got := testutil.ToFloat64(inventoryObjects.WithLabelValues(ip, ModuleVolume)); got != 3 {
+		t.Errorf("powerstore_inventory_objects = %v volumes, want 3", got)
+	}
+}
+
+func TestInitModuleID(t *testing.T) {
+	var lock sync.Mutex
+	collections := map[string]string{
+		"appliance":                  `[{"id":"A1","name":"appliance-1"}]`,
+		"volume_list_cma_view":       `[{"id":"v1","name":"volume-1","appliance_id":"A1"},{"id":"v2","name":"volume-2","appliance_id":"A1"}]`,
+		"volume_group_list_cma_view": `[{"id":"vg1","name":"group-1","appliance_ids":["A1"]}]`,
+		"eth_port":                   `[{"id":"e1","name":"eth-1","appliance_id":"A1","node_id":"N1"}]`,
+		"fc_port":                    `[{"id":"f1","name":"fc-1","appliance_id":"A1","node_id":"N2"}]`,
+		"hardware":                   `[{"id":"d1","name":"Drive_0","appliance_id":"A1","type":"Drive","parent_id":"enc1"}]`,
+		"nas_server_list_cma_view":   `[{"id":"n1","name":"nas-1"}]`,
+		"file_system":                `[{"id":"fs1","name":"fs-1","nas_server_id":"n1"}]`,
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		body, ok := collections[path.Base(r.URL.Path)]
+		lock.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"messages":[{"code":"0xE04040010005"}]}`))
+			return
+		}
+		w.Write([]byte(body))
+	})
+	c.InitModuleID(context.Background(), log.NewNopLogger())
+	inventory := c.Inventory()
+	for _, test := range []struct {
+		module string
+		id     string
+		want   Entry
+	}{
+		{ModuleAppliance, "A1", Entry{ID: "A1", Name: "appliance-1", ApplianceID: "A1", Type: ModuleAppliance}},
+		{ModuleVolumeGroup, "vg1", Entry{ID: "vg1", Name: "group-1", ApplianceID: "A1", Type: ModuleVolumeGroup}},
+		{ModuleEthPort, "e1", Entry{ID: "e1", Name: "eth-1", ApplianceID: "A1", Type: ModuleEthPort, Parent: "N1"}},
+		{ModuleFcPort, "f1", Entry{ID: "f1", Name: "fc-1", ApplianceID: "A1", Type: ModuleFcPort, Parent: "N2"}},
+		{ModuleDrive, "d1", Entry{ID: "d1", Name: "Drive_0", ApplianceID: "A1", Type: "Drive", Parent: "enc1"}},
+		{ModuleFilesystem, "fs1", Entry{ID: "fs1", Name: "fs-1", Type: ModuleFilesystem, Parent: "n1"}},
+	} {
+		if got, ok := inventory.Lookup(test.module, test.id); !ok || got != test.want {
+			t.Errorf("Lookup(%s, %s) = %+v, %v, want %+v", test.module, test.id, got, ok, test.want)
+		}
+	}
+
+	// the eth ports fail to load and a volume is deleted and another created
+	lock.Lock()
+	delete(collections, "eth_port")
+	collections["volume_list_cma_view"] = `[{"id":"v2","name":"volume-2","appliance_id":"A1"},{"id":"v3","name":"volume-3","appliance_id":"A1"}]`
+	lock.Unlock()
+	c.InitModuleID(context.Background(), log.NewNopLogger())
+	if _, ok := inventory.Lookup(ModuleEthPort, "e1"); !ok {
+		t.Error("the eth ports of the previous load were dropped by a failed refresh")
+	}
+	if added, removed := changes(c.IP, ModuleEthPort); added != 0 || removed != 0 {
+		t.Errorf("eth port changes = %v added, %v removed after a failed refresh, want none", added, removed)
+	}
+	if _, ok := inventory.Lookup(ModuleVolume, "v1"); ok {
+		t.Error("the deleted volume v1 is still in the inventory")
+	}
+	if _, ok := inventory.Lookup(ModuleVolume, "v3"); !ok {
+		t.Error("the created volume v3 is not in the inventory")
+	}
+	if added, removed := changes(c.IP, ModuleVolume); added != 1 || removed != 1 {
+		t.Errorf("volume changes = %v added, %v removed, want 1 added, 1 removed", added, removed)
+	}
+}
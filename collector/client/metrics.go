@@ -0,0 +1,67 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// The metrics of the powerstore rest api calls, exposed on the exporter /performance endpoint
+var (
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "powerstore_api_request_duration_seconds",
+		Help:    "Latency of the powerstore rest api requests,unit is s",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"IP", "method", "endpoint"})
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "powerstore_api_requests_total",
+		Help: "Number of powerstore rest api requests by http status code,code is error when no response was received",
+	}, []string{"IP", "method", "endpoint", "code"})
+	apiRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "powerstore_api_retries_total",
+		Help: "Number of powerstore rest api requests sent again after a transient error,the requests sent again after a relogin are counted in powerstore_api_relogins_total",
+	}, []string{"IP", "method", "endpoint"})
+	apiRelogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "powerstore_api_relogins_total",
+		Help: "Number of logins caused by an expired powerstore authentication token",
+	}, []string{"IP"})
+	apiResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "powerstore_api_response_size_bytes",
+		Help:    "Size of the powerstore rest api response bodies,unit is B",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 10),
+	}, []string{"IP", "method", "endpoint"})
+)
+
+func init() {
+	prometheus.MustRegister(apiRequestDuration, apiRequests, apiRetries, apiRelogins, apiResponseSize)
+}
+
+// endpointOf Returns the logical endpoint of a request for the metric labels,
+// the resource path without the query, with the entity appended for metrics/generate
+func endpointOf(uri, body string) string {
+	endpoint := uri
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	if entity := gjson.Get(body, "entity"); entity.Exists() {
+		endpoint += ":" + entity.String()
+	}
+	return endpoint
+}
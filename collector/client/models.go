@@ -0,0 +1,284 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The resources of the powerstore rest api. Only the fields used by the exporter are decoded, the others are ignored.
+// The optional values are pointers, nil when the powerstore returns null or leaves them out.
+
+type Cluster struct {
+	ID                string `json:"id"`
+	GlobalID          string `json:"global_id"`
+	Name              string `json:"name"`
+	ManagementAddress string `json:"management_address"`
+	MasterApplianceID string `json:"master_appliance_id"`
+	State             string `json:"state"`
+}
+
+type Appliance struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	ServiceTag *string `json:"service_tag"`
+}
+
+// Hardware A hardware component, e.g. a node, drive, fan, power supply or battery
+type Hardware struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	ApplianceID    string          `json:"appliance_id"`
+	ParentID       string          `json:"parent_id"`
+	SerialNumber   string          `json:"serial_number"`
+	LifecycleState *string         `json:"lifecycle_state"`
+	ExtraDetails   HardwareDetails `json:"extra_details"`
+}
+
+// HardwareDetails The details of a hardware component depending on its type
+type HardwareDetails struct {
+	Size      *float64 `json:"size"`
+	DriveType string   `json:"drive_type"`
+}
+
+// Node A node of an appliance, returned by the hardware resource with type Node
+type Node = Hardware
+
+// Port An eth_port or fc_port
+type Port struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	ApplianceID  string  `json:"appliance_id"`
+	NodeID       string  `json:"node_id"`
+	IsLinkUp     *bool   `json:"is_link_up"`
+	CurrentSpeed *string `json:"current_speed"`
+}
+
+type Volume struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	ApplianceID string   `json:"appliance_id"`
+	Type        string   `json:"type"`
+	State       string   `json:"state"`
+	Size        *float64 `json:"size"`
+	LogicalUsed *float64 `json:"logical_used"`
+}
+
+type VolumeGroup struct {
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	ApplianceIDs       []string `json:"appliance_ids"`
+	LogicalProvisioned *float64 `json:"logical_provisioned"`
+	LogicalUsed        *float64 `json:"logical_used"`
+}
+
+type NasServer struct {
+	ID                string  `json:"id"`
+	Name              string  `json:"name"`
+	OperationalStatus *string `json:"operational_status"`
+}
+
+type FileSystem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	NasServerID string `json:"nas_server_id"`
+}
+
+// Sample A sample of a metrics/generate entity
+type Sample interface {
+	SampleTime() time.Time
+}
+
+// MetricSample The fields shared by the samples of every metrics/generate entity
+type MetricSample struct {
+	Timestamp   time.Time `json:"timestamp"`
+	ApplianceID string    `json:"appliance_id"`
+}
+
+// SampleTime Returns the powerstore timestamp of the sample, zero when it has none
+func (s MetricSample) SampleTime() time.Time {
+	return s.Timestamp
+}
+
+// IoPerformance The latency, iops, bandwidth and size averages of the performance entities
+type IoPerformance struct {
+	AvgReadLatency    *float64 `json:"avg_read_latency"`
+	AvgLatency        *float64 `json:"avg_latency"`
+	AvgWriteLatency   *float64 `json:"avg_write_latency"`
+	AvgReadIops       *float64 `json:"avg_read_iops"`
+	AvgReadBandwidth  *float64 `json:"avg_read_bandwidth"`
+	AvgTotalIops      *float64 `json:"avg_total_iops"`
+	AvgTotalBandwidth *float64 `json:"avg_total_bandwidth"`
+	AvgWriteIops      *float64 `json:"avg_write_iops"`
+	AvgWriteBandwidth *float64 `json:"avg_write_bandwidth"`
+	AvgReadSize       *float64 `json:"avg_read_size"`
+	AvgWriteSize      *float64 `json:"avg_write_size"`
+}
+
+// AppliancePerformance A sample of performance_metrics_by_appliance
+type AppliancePerformance struct {
+	MetricSample
+	IoPerformance
+	AvgIoSize                   *float64 `json:"avg_io_size"`
+	AvgIoWorkloadCpuUtilization *float64 `json:"avg_io_workload_cpu_utilization"`
+}
+
+// NodePerformance A sample of performance_metrics_by_node
+type NodePerformance struct {
+	MetricSample
+	IoPerformance
+	NodeID                      string   `json:"node_id"`
+	AvgIoSize                   *float64 `json:"avg_io_size"`
+	AvgIoWorkloadCpuUtilization *float64 `json:"avg_io_workload_cpu_utilization"`
+	AvgCurrentLogins            *float64 `json:"avg_current_logins"`
+}
+
+// VolumePerformance A sample of performance_metrics_by_volume
+type VolumePerformance struct {
+	MetricSample
+	IoPerformance
+	VolumeID  string   `json:"volume_id"`
+	AvgIoSize *float64 `json:"avg_io_size"`
+}
+
+// VgPerformance A sample of performance_metrics_by_vg
+type VgPerformance struct {
+	MetricSample
+	IoPerformance
+	VgID      string   `json:"vg_id"`
+	AvgIoSize *float64 `json:"avg_io_size"`
+}
+
+// NasPerformance A sample of performance_metrics_by_nas_server
+type NasPerformance struct {
+	MetricSample
+	IoPerformance
+	NasServerID string   `json:"nas_server_id"`
+	AvgSize     *float64 `json:"avg_size"`
+}
+
+// FilesystemPerformance A sample of performance_metrics_by_file_system
+type FilesystemPerformance struct {
+	MetricSample
+	IoPerformance
+	FileSystemID             string   `json:"file_system_id"`
+	AvgSize                  *float64 `json:"avg_size"`
+	AvgBlockWriteIops        *float64 `json:"avg_block_write_iops"`
+	AvgMirrorWriteIops       *float64 `json:"avg_mirror_write_iops"`
+	AvgBlockWriteBandwidth   *float64 `json:"avg_block_write_bandwidth"`
+	AvgMirrorWriteBandwidth  *float64 `json:"avg_mirror_write_bandwidth"`
+	AvgBlockWriteLatency     *float64 `json:"avg_block_write_latency"`
+	AvgMirrorOverheadLatency *float64 `json:"avg_mirror_overhead_latency"`
+}
+
+// FcPortPerformance A sample of performance_metrics_by_fe_fc_port
+type FcPortPerformance struct {
+	MetricSample
+	FePortID                 string   `json:"fe_port_id"`
+	AvgReadLatency           *float64 `json:"avg_read_latency"`
+	AvgLatency               *float64 `json:"avg_latency"`
+	AvgWriteLatency          *float64 `json:"avg_write_latency"`
+	AvgTotalIops             *float64 `json:"avg_total_iops"`
+	AvgTotalBandwidth        *float64 `json:"avg_total_bandwidth"`
+	AvgDumpedFramesPs        *float64 `json:"avg_dumped_frames_ps"`
+	AvgLossOfSignalCountPs   *float64 `json:"avg_loss_of_signal_count_ps"`
+	AvgInvalidCrcCountPs     *float64 `json:"avg_invalid_crc_count_ps"`
+	AvgLossOfSyncCountPs     *float64 `json:"avg_loss_of_sync_count_ps"`
+	AvgInvalidTxWordCountPs  *float64 `json:"avg_invalid_tx_word_count_ps"`
+	AvgPrimSeqProtErrCountPs *float64 `json:"avg_prim_seq_prot_err_count_ps"`
+	AvgLinkFailureCountPs    *float64 `json:"avg_link_failure_count_ps"`
+}
+
+// EthPortPerformance A sample of performance_metrics_by_fe_eth_port
+type EthPortPerformance struct {
+	MetricSample
+	FePortID                string   `json:"fe_port_id"`
+	AvgBytesRxPs            *float64 `json:"avg_bytes_rx_ps"`
+	AvgBytesTxPs            *float64 `json:"avg_bytes_tx_ps"`
+	AvgPktRxCrcErrorPs      *float64 `json:"avg_pkt_rx_crc_error_ps"`
+	AvgPktRxNoBufferErrorPs *float64 `json:"avg_pkt_rx_no_buffer_error_ps"`
+	AvgPktRxPs              *float64 `json:"avg_pkt_rx_ps"`
+	AvgPktTxErrorPs         *float64 `json:"avg_pkt_tx_error_ps"`
+	AvgPktTxPs              *float64 `json:"avg_pkt_tx_ps"`
+}
+
+// ApplianceSpace A sample of space_metrics_by_appliance
+type ApplianceSpace struct {
+	MetricSample
+	LastLogicalProvisioned *float64 `json:"last_logical_provisioned"`
+	LastLogicalUsed        *float64 `json:"last_logical_used"`
+	LastPhysicalTotal      *float64 `json:"last_physical_total"`
+	LastPhysicalUsed       *float64 `json:"last_physical_used"`
+	MaxLogicalProvisioned  *float64 `json:"max_logical_provisioned"`
+	MaxLogicalUsed         *float64 `json:"max_logical_used"`
+	MaxPhysicalTotal       *float64 `json:"max_physical_total"`
+	MaxPhysicalUsed        *float64 `json:"max_physical_used"`
+	LastDataPhysicalUsed   *float64 `json:"last_data_physical_used"`
+	MaxDataPhysicalUsed    *float64 `json:"max_data_physical_used"`
+	LastEfficiencyRatio    *float64 `json:"last_efficiency_ratio"`
+	LastDataReduction      *float64 `json:"last_data_reduction"`
+	LastSnapshotSavings    *float64 `json:"last_snapshot_savings"`
+	LastThinSavings        *float64 `json:"last_thin_savings"`
+	MaxEfficiencyRatio     *float64 `json:"max_efficiency_ratio"`
+	MaxDataReduction       *float64 `json:"max_data_reduction"`
+	MaxSnapshotSavings     *float64 `json:"max_snapshot_savings"`
+	MaxThinSavings         *float64 `json:"max_thin_savings"`
+	LastSharedLogicalUsed  *float64 `json:"last_shared_logical_used"`
+	MaxSharedLogicalUsed   *float64 `json:"max_shared_logical_used"`
+}
+
+// FilesystemSpace A sample of space_metrics_by_file_system
+type FilesystemSpace struct {
+	MetricSample
+	FileSystemID       string   `json:"file_system_id"`
+	LogicalProvisioned *float64 `json:"logical_provisioned"`
+	LogicalUsed        *float64 `json:"logical_used"`
+	ThinSavings        *float64 `json:"thin_savings"`
+}
+
+// DriveWear A sample of wear_metrics_by_drive
+type DriveWear struct {
+	MetricSample
+	DriveID                   string   `json:"drive_id"`
+	PercentEnduranceRemaining *float64 `json:"percent_endurance_remaining"`
+}
+
+// DecodeError The response of the powerstore does not match the model
+type DecodeError struct {
+	Resource string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode %s response: %v", e.Resource, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeList Decode a list of resources or samples, the unknown fields are ignored
+func decodeList[T any](resource, data string) ([]T, error) {
+	var list []T
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		return nil, &DecodeError{Resource: resource, Err: err}
+	}
+	return list, nil
+}
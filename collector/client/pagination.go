@@ -0,0 +1,125 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/tidwall/gjson"
+)
+
+// getCollection Returns all the items of a collection query. The powerstore caps the number of items
+// of a response and returns the rest in pages, the Content-Range header tells which items were returned
+// and the following pages are requested with offset until the total is reached.
+func (c *Client) getCollection(ctx context.Context, uri string) (string, error) {
+	result, contentRange, err := c.getResource(ctx, "GET", uri, "")
+	// an empty collection has no range of items, e.g. */0
+	if err != nil || contentRange == "" || strings.HasPrefix(contentRange, "*") {
+		return result, err
+	}
+	_, last, total, err := parseContentRange(contentRange)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "ignore invalid Content-Range", "uri", uri, "err", err)
+		return result, nil
+	}
+	if last+1 >= total {
+		return result, nil
+	}
+	items, err := rawItems(result)
+	if err != nil {
+		return "", fmt.Errorf("page 1 of %s: %v", uri, err)
+	}
+	pages := 1
+	for last+1 < total {
+		page, contentRange, err := c.getResource(ctx, "GET", withOffset(uri, last+1), "")
+		if err != nil {
+			return "", err
+		}
+		first, next, pageTotal, err := parseContentRange(contentRange)
+		if err != nil {
+			return "", fmt.Errorf("page %d of %s: %v", pages+1, uri, err)
+		}
+		if first != last+1 || next < first {
+			return "", fmt.Errorf("page %d of %s: unexpected Content-Range %q after item %d", pages+1, uri, contentRange, last)
+		}
+		pageItems, err := rawItems(page)
+		if err != nil {
+			return "", fmt.Errorf("page %d of %s: %v", pages+1, uri, err)
+		}
+		items = append(items, pageItems...)
+		last, total = next, pageTotal
+		pages++
+	}
+	level.Debug(c.logger).Log("msg", "collection returned in pages", "ip", c.IP, "uri", uri, "pages", pages, "items", len(items))
+	return "[" + strings.Join(items, ",") + "]", nil
+}
+
+// parseContentRange Parse a Content-Range header of the powerstore, e.g. 0-99/1000
+func parseContentRange(contentRange string) (first, last, total int, err error) {
+	invalid := fmt.Errorf("invalid Content-Range %q", contentRange)
+	items, size, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(contentRange), "items "), "/")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	from, to, ok := strings.Cut(items, "-")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	if first, err = strconv.Atoi(from); err != nil {
+		return 0, 0, 0, invalid
+	}
+	if last, err = strconv.Atoi(to); err != nil {
+		return 0, 0, 0, invalid
+	}
+	if total, err = strconv.Atoi(size); err != nil {
+		return 0, 0, 0, invalid
+	}
+	if first < 0 || last < first || total <= last {
+		return 0, 0, 0, invalid
+	}
+	return first, last, total, nil
+}
+
+// withOffset Returns the query uri starting at the item offset, replacing the offset of the query if any
+func withOffset(uri string, offset int) string {
+	path, query, _ := strings.Cut(uri, "?")
+	var params []string
+	for _, param := range strings.Split(query, "&") {
+		if param != "" && !strings.HasPrefix(param, "offset=") {
+			params = append(params, param)
+		}
+	}
+	params = append(params, "offset="+strconv.Itoa(offset))
+	return path + "?" + strings.Join(params, "&")
+}
+
+// rawItems Returns the raw json of the items of a collection page, an error when the page is not a list
+func rawItems(page string) ([]string, error) {
+	result := gjson.Parse(page)
+	if !result.IsArray() {
+		return nil, fmt.Errorf("the page is not a list: %.100s", page)
+	}
+	var items []string
+	for _, item := range result.Array() {
+		items = append(items, item.Raw)
+	}
+	return items, nil
+}
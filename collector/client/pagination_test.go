@@ -0,0 +1,99 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header             string
+		first, last, total int
+		wantErr            bool
+	}{
+		{"0-99/1000", 0, 99, 1000, false},
+		{"items 0-1999/2500", 0, 1999, 2500, false},
+		{" 100-199/200 ", 100, 199, 200, false},
+		// the last page, smaller than the ones before
+		{"2000-2499/2500", 2000, 2499, 2500, false},
+		{"4-4/5", 4, 4, 5, false},
+		// the totals unknown or empty are not ranges of items, getCollection returns such a response as is
+		{"*/0", 0, 0, 0, true},
+		{"*/12", 0, 0, 0, true},
+		{"0-99/*", 0, 0, 0, true},
+		{"", 0, 0, 0, true},
+		{"0-99", 0, 0, 0, true},
+		{"99/1000", 0, 0, 0, true},
+		{"a-99/1000", 0, 0, 0, true},
+		{"0-b/1000", 0, 0, 0, true},
+		{"0-99/c", 0, 0, 0, true},
+		{"-1-99/1000", 0, 0, 0, true},
+		{"99-0/1000", 0, 0, 0, true},
+		{"0-99/99", 0, 0, 0, true},
+		{"bytes 0-99/1000", 0, 0, 0, true},
+	}
+	for _, test := range tests {
+		first, last, total, err := parseContentRange(test.header)
+		if (err != nil) != test.wantErr || first != test.first || last != test.last || total != test.total {
+			t.Errorf("parseContentRange(%q) = %d, %d, %d, %v, want %d, %d, %d, error %v",
+				test.header, first, last, total, err, test.first, test.last, test.total, test.wantErr)
+		}
+	}
+}
+
+func TestWithOffset(t *testing.T) {
+	tests := []struct {
+		uri    string
+		offset int
+		want   string
+	}{
+		{"volume", 100, "volume?offset=100"},
+		{"volume?", 100, "volume?offset=100"},
+		{"volume?select=*&limit=100", 100, "volume?select=*&limit=100&offset=100"},
+		{"hardware?select=*&type=eq.Drive&limit=2", 2, "hardware?select=*&type=eq.Drive&limit=2&offset=2"},
+		{"volume?select=*&offset=100&limit=100", 200, "volume?select=*&limit=100&offset=200"},
+		{"volume?offset=0", 5, "volume?offset=5"},
+	}
+	for _, test := range tests {
+		if got := withOffset(test.uri, test.offset); got != test.want {
+			t.Errorf("withOffset(%q, %d) = %q, want %q", test.uri, test.offset, got, test.want)
+		}
+	}
+}
+
+func TestRawItems(t *testing.T) {
+	tests := []struct {
+		page    string
+		want    []string
+		wantErr bool
+	}{
+		{`[{"id":"1"},{"id":"2"}]`, []string{`{"id":"1"}`, `{"id":"2"}`}, false},
+		{` [ {"id":"1"} ] `, []string{`{"id":"1"}`}, false},
+		{`[]`, nil, false},
+		{``, nil, true},
+		{`{"messages":[]}`, nil, true},
+		{`"volume"`, nil, true},
+	}
+	for _, test := range tests {
+		got, err := rawItems(test.page)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("rawItems(%q) = %q, %v, want %q, error %v", test.page, got, err, test.want, test.wantErr)
+		}
+	}
+}
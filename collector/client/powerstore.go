@@ -0,0 +1,56 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import "context"
+
+// PowerStoreAPI The powerstore data read by the collectors, implemented by Client and by fakes in tests
+type PowerStoreAPI interface {
+	// Address Returns the ip of the powerstore, the IP label of its metrics
+	Address() string
+	// Inventory Returns the objects of the powerstore the metrics are generated for
+	Inventory() *Inventory
+
+	GetCluster(ctx context.Context) ([]Cluster, error)
+	GetPort(ctx context.Context, portType string) ([]Port, error)
+	GetHardware(ctx context.Context, hardwareType string) ([]Hardware, error)
+	GetVolume(ctx context.Context) ([]Volume, error)
+	GetAppliance(ctx context.Context) ([]Appliance, error)
+	GetNas(ctx context.Context) ([]NasServer, error)
+	GetNasDetail(ctx context.Context) ([]NasServer, error)
+	GetVolumeGroup(ctx context.Context) ([]VolumeGroup, error)
+
+	GetPerf(ctx context.Context, id, interval string) ([]AppliancePerformance, error)
+	GetCap(ctx context.Context, id, interval string) ([]ApplianceSpace, error)
+	GetMetricVg(ctx context.Context, id, interval string) ([]VgPerformance, error)
+	GetMetricVolume(ctx context.Context, id, interval string) ([]VolumePerformance, error)
+	GetMetricFcPort(ctx context.Context, id, interval string) ([]FcPortPerformance, error)
+	GetMetricEthPort(ctx context.Context, id, interval string) ([]EthPortPerformance, error)
+	GetMetricAppliance(ctx context.Context, id, interval string) ([]AppliancePerformance, error)
+	GetMetricNode(ctx context.Context, id, interval string) ([]NodePerformance, error)
+	GetWearMetricByDrive(ctx context.Context, id, interval string) ([]DriveWear, error)
+	GetMetricByNas(ctx context.Context, id, interval string) ([]NasPerformance, error)
+	GetFilesystemCap(ctx context.Context, id, interval string) ([]FilesystemSpace, error)
+	GetMetricsFilesystem(ctx context.Context, id, interval string) ([]FilesystemPerformance, error)
+}
+
+var _ PowerStoreAPI = (*Client)(nil)
+
+// Address Returns the ip of the powerstore
+func (c *Client) Address() string {
+	return c.IP
+}
@@ -0,0 +1,140 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"powerstore-metrics-exporter/utils"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryAttempts  = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+	// maxRetryAfter A request the powerstore asks to send again later than this is not retried
+	maxRetryAfter = time.Minute
+)
+
+// StatusError The powerstore answered the request with an error status
+type StatusError struct {
+	Code int
+	// RetryAfter The delay asked by the Retry-After header, zero when there is none
+	RetryAfter time.Duration
+	message    string
+}
+
+func (e *StatusError) Error() string {
+	return e.message
+}
+
+// retryPolicy How the failed idempotent requests of a client are retried
+type retryPolicy struct {
+	attempts       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func newRetryPolicy(config utils.Retry) retryPolicy {
+	policy := retryPolicy{
+		attempts:       config.Attempts,
+		initialBackoff: time.Duration(config.InitialBackoff * float64(time.Second)),
+		maxBackoff:     time.Duration(config.MaxBackoff * float64(time.Second)),
+	}
+	if policy.attempts <= 0 {
+		policy.attempts = defaultRetryAttempts
+	}
+	if policy.initialBackoff <= 0 {
+		policy.initialBackoff = defaultInitialBackoff
+	}
+	if policy.maxBackoff <= 0 {
+		policy.maxBackoff = defaultMaxBackoff
+	}
+	if policy.maxBackoff < policy.initialBackoff {
+		policy.maxBackoff = policy.initialBackoff
+	}
+	return policy
+}
+
+// delay Returns how long to wait before the next attempt of a request that failed with err,
+// false when it must not be retried
+func (p retryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.attempts || !retryable(err) {
+		return 0, false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if statusErr.RetryAfter > maxRetryAfter {
+			return 0, false
+		}
+		return statusErr.RetryAfter, true
+	}
+	backoff := p.initialBackoff
+	for i := 1; i < attempt && backoff < p.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.maxBackoff {
+		backoff = p.maxBackoff
+	}
+	// the retries of the requests failed at the same time are spread over the second half of the backoff
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), true
+}
+
+// retryable Whether the error may not happen again, e.g. the powerstore is busy or the connection was reset
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var handshakeErr *HandshakeError
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &handshakeErr) {
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// idempotent Whether sending the request again has no other effect, metrics/generate only reads the metrics
+func idempotent(method, uri string) bool {
+	return method == http.MethodGet || method == http.MethodHead || uri == "metrics/generate"
+}
+
+// retryAfter Parse the Retry-After header, either seconds or a http date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"powerstore-metrics-exporter/utils"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitScheduler(10)
+	os.Exit(m.Run())
+}
+
+// newTestClient Returns a client of a powerstore answering the login and passing the other requests to handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login_session") {
+			w.Header().Set("DELL-EMC-TOKEN", "token")
+			w.Write([]byte("[]"))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	sum := sha256.Sum256(server.Certificate().Raw)
+	client, err := NewClient(utils.Storage{
+		Ip:       server.Listener.Addr().String(),
+		User:     "admin",
+		Password: "password",
+		Version:  "v3",
+		TLS:      utils.TLS{Fingerprint: hex.EncodeToString(sum[:])},
+		Retry:    utils.Retry{Attempts: 3, InitialBackoff: 0.001, MaxBackoff: 0.001},
+	}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"none", "", 0, 0},
+		{"seconds", "5", 5 * time.Second, 5 * time.Second},
+		{"zero seconds", "0", 0, 0},
+		{"negative seconds", "-5", 0, 0},
+		{"date", time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), 28 * time.Second, 30 * time.Second},
+		{"past date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+		{"garbage", "soon", 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := retryAfter(test.header); got < test.min || got > test.max {
+				t.Errorf("retryAfter(%q) = %v, want between %v and %v", test.header, got, test.min, test.max)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := retryPolicy{attempts: 4, initialBackoff: 100 * time.Millisecond, maxBackoff: 300 * time.Millisecond}
+	busy := &StatusError{Code: http.StatusServiceUnavailable}
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		min     time.Duration
+		max     time.Duration
+		retried bool
+	}{
+		{"first backoff", 1, busy, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{"doubled backoff", 2, busy, 100 * time.Millisecond, 200 * time.Millisecond, true},
+		{"capped backoff", 3, busy, 150 * time.Millisecond, 300 * time.Millisecond, true},
+		{"last attempt", 4, busy, 0, 0, false},
+		{"retry after", 1, &StatusError{Code: http.StatusTooManyRequests, RetryAfter: 20 * time.Second}, 20 * time.Second, 20 * time.Second, true},
+		{"retry after at the cap", 1, &StatusError{Code: http.StatusTooManyRequests, RetryAfter: maxRetryAfter}, maxRetryAfter, maxRetryAfter, true},
+		{"retry after beyond the cap", 1, &StatusError{Code: http.StatusTooManyRequests, RetryAfter: maxRetryAfter + time.Second}, 0, 0, false},
+		{"no response", 1, &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: errors.New("connection reset")}, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{"truncated response", 1, io.ErrUnexpectedEOF, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{"client error", 1, &StatusError{Code: http.StatusBadRequest}, 0, 0, false},
+		{"server error", 1, &StatusError{Code: http.StatusInternalServerError}, 0, 0, false},
+		{"cancelled", 1, &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: context.Canceled}, 0, 0, false},
+		{"handshake", 1, &HandshakeError{Err: errors.New("bad certificate")}, 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delay, retried := policy.delay(test.attempt, test.err)
+			if retried != test.retried || delay < test.min || delay > test.max {
+				t.Errorf("delay(%d, %v) = %v, %v, want between %v and %v, %v",
+					test.attempt, test.err, delay, retried, test.min, test.max, test.retried)
+			}
+		})
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		uri    string
+		want   bool
+	}{
+		{http.MethodGet, "volume", true},
+		{http.MethodHead, "volume", true},
+		{http.MethodPost, "metrics/generate", true},
+		{http.MethodPost, "volume", false},
+		{http.MethodPost, "volume/1/snapshot", false},
+		{http.MethodDelete, "volume/1", false},
+	}
+	for _, test := range tests {
+		if got := idempotent(test.method, test.uri); got != test.want {
+			t.Errorf("idempotent(%s %s) = %v, want %v", test.method, test.uri, got, test.want)
+		}
+	}
+}
+
+func TestGetResourceRetries(t *testing.T) {
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		lock.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	tests := []struct {
+		method string
+		uri    string
+		want   int
+	}{
+		{http.MethodGet, "volume", 3},
+		{http.MethodPost, "metrics/generate", 3},
+		{http.MethodPost, "volume", 1},
+	}
+	for _, test := range tests {
+		_, _, err := c.getResource(context.Background(), test.method, test.uri, "{}")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s %s error = %v, want the 503", test.method, test.uri, err)
+		}
+		lock.Lock()
+		got := requests[test.method+" /api/rest/"+test.uri]
+		lock.Unlock()
+		if got != test.want {
+			t.Errorf("%s %s sent %d times, want %d", test.method, test.uri, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/log/level"
+)
+
+// maxRelogins The number of logins a request may trigger before its 401 is returned
+const maxRelogins = 1
+
+// session The authentication token and cookie of a client, safe for concurrent use.
+// The generation is increased by every login so that a request knows whether its token was already replaced.
+type session struct {
+	lock       sync.RWMutex
+	token      string
+	cookie     string
+	generation uint64
+}
+
+func (s *session) get() (token, cookie string, generation uint64) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.token, s.cookie, s.generation
+}
+
+func (s *session) set(token, cookie string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.token = token
+	s.cookie = cookie
+	s.generation++
+}
+
+// Logout End the session of the client on the powerstore, e.g. on exporter shutdown.
+// The probes of the circuit breaker are stopped first so that they do not log in again.
+func (c *Client) Logout(ctx context.Context) error {
+	c.breaker.stop()
+	c.loginLock.Lock()
+	defer c.loginLock.Unlock()
+	token, cookie, _ := c.session.get()
+	if token == "" {
+		return nil
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", c.baseUrl+"logout", nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("DELL-EMC-TOKEN", token)
+	request.Header.Set("Cookie", "auth_cookie="+cookie)
+	response, err := c.http.Do(request)
+	if err != nil {
+		return c.requestError(err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	apiRequests.WithLabelValues(c.IP, "POST", "logout", strconv.Itoa(response.StatusCode)).Inc()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New("logout error: " + string(body))
+	}
+	c.session.set("", "")
+	level.Info(c.logger).Log("msg", "logged out of the powerstore", "ip", c.IP)
+	return nil
+}
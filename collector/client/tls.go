@@ -0,0 +1,145 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"powerstore-metrics-exporter/utils"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var tlsHandshakeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "powerstore_tls_handshake_failures_total",
+	Help: "Number of failed TLS handshakes with the powerstore, e.g. an untrusted or unpinned certificate",
+}, []string{"IP"})
+
+func init() {
+	prometheus.MustRegister(tlsHandshakeFailures)
+}
+
+// HandshakeError The TLS handshake with the powerstore failed, the request was not sent
+type HandshakeError struct {
+	IP  string
+	Err error
+}
+
+func (e *HandshakeError) Error() string {
+	return "tls handshake with powerstore " + e.IP + " failed: " + e.Err.Error()
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// tlsHandshakeTimeout How long the TLS handshake with the powerstore may take, the transport does not apply its
+// TLSHandshakeTimeout to the handshakes of DialTLSContext
+const tlsHandshakeTimeout = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig Build the TLS config of a powerstore from its storage config
+func newTLSConfig(config utils.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if config.MinVersion != "" {
+		version, ok := tlsVersions[config.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls minVersion %q is not supported, supported versions are 1.0, 1.1, 1.2, 1.3", config.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls caFile: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls caFile %s has no PEM certificate", config.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if config.Fingerprint != "" {
+		fingerprint, err := hex.DecodeString(strings.ReplaceAll(config.Fingerprint, ":", ""))
+		if err != nil || len(fingerprint) != sha256.Size {
+			return nil, fmt.Errorf("tls fingerprint %q is not a SHA-256 in hex", config.Fingerprint)
+		}
+		// the pinned certificate is trusted as is, e.g. the self-signed certificate of the powerstore,
+		// the chain is still verified when certificate authorities are configured
+		if config.CAFile == "" {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("no certificate to verify the pinned fingerprint")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if !bytes.Equal(sum[:], fingerprint) {
+				return fmt.Errorf("certificate fingerprint %s does not match the pinned fingerprint", hex.EncodeToString(sum[:]))
+			}
+			return nil
+		}
+	}
+	return tlsConfig, nil
+}
+
+// dialTLS Returns the dialer of the powerstore connections, doing the handshake itself so that its failures are told apart
+func dialTLS(ip string, dialer *net.Dialer, config *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := config.Clone()
+		if tlsConfig.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			tlsConfig.ServerName = host
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		handshakeCtx, cancel := context.WithTimeout(ctx, tlsHandshakeTimeout)
+		defer cancel()
+		if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+			conn.Close()
+			tlsHandshakeFailures.WithLabelValues(ip).Inc()
+			return nil, &HandshakeError{IP: ip, Err: err}
+		}
+		return tlsConn, nil
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"powerstore-metrics-exporter/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTLSServer Start a https server with the self-signed certificate of httptest, valid for 127.0.0.1 and example.com,
+// and returns it with the file of its certificate and its fingerprint
+func newTLSServer(t *testing.T, maxVersion uint16) (*httptest.Server, string, string) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: maxVersion}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(server.Certificate().Raw)
+	return server, caFile, hex.EncodeToString(sum[:])
+}
+
+// handshake Dial the server with the TLS config like the transport of the client, the error is returned
+func handshake(t *testing.T, ip string, server *httptest.Server, config utils.TLS) error {
+	t.Helper()
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := dialTLS(ip, &net.Dialer{Timeout: 5 * time.Second}, tlsConfig)
+	conn, err := dial(context.Background(), "tcp", server.Listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+func TestDialTLS(t *testing.T) {
+	server, caFile, fingerprint := newTLSServer(t, 0)
+	otherFingerprint := strings.Repeat("00", sha256.Size)
+	tests := []struct {
+		name    string
+		config  utils.TLS
+		wantErr bool
+	}{
+		{"system roots", utils.TLS{}, true},
+		{"ca file", utils.TLS{CAFile: caFile}, false},
+		{"server name in the certificate", utils.TLS{CAFile: caFile, ServerName: "example.com"}, false},
+		{"server name not in the certificate", utils.TLS{CAFile: caFile, ServerName: "powerstore.invalid"}, true},
+		{"pinned fingerprint", utils.TLS{Fingerprint: fingerprint}, false},
+		{"pinned fingerprint with colons", utils.TLS{Fingerprint: colons(fingerprint)}, false},
+		{"other fingerprint", utils.TLS{Fingerprint: otherFingerprint}, true},
+		{"other fingerprint and ca file", utils.TLS{CAFile: caFile, Fingerprint: otherFingerprint}, true},
+		{"other fingerprint and insecure", utils.TLS{InsecureSkipVerify: true, Fingerprint: otherFingerprint}, true},
+		{"insecure", utils.TLS{InsecureSkipVerify: true}, false},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ip := "tls-dial-" + string(rune('a'+i))
+			err := handshake(t, ip, server, test.config)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("handshake error = %v, want error %v", err, test.wantErr)
+			}
+			failures := testutil.ToFloat64(tlsHandshakeFailures.WithLabelValues(ip))
+			if err == nil {
+				if failures != 0 {
+					t.Errorf("powerstore_tls_handshake_failures_total = %v after a handshake, want 0", failures)
+				}
+				return
+			}
+			var handshakeErr *HandshakeError
+			if !errors.As(err, &handshakeErr) || handshakeErr.IP != ip {
+				t.Errorf("handshake error = %#v, want a HandshakeError of %s", err, ip)
+			}
+			if failures != 1 {
+				t.Errorf("powerstore_tls_handshake_failures_total = %v after a failed handshake, want 1", failures)
+			}
+		})
+	}
+}
+
+func TestDialTLSMinVersion(t *testing.T) {
+	server, caFile, _ := newTLSServer(t, tls.VersionTLS12)
+	if err := handshake(t, "tls-version-12", server, utils.TLS{CAFile: caFile, MinVersion: "1.2"}); err != nil {
+		t.Errorf("handshake with minVersion 1.2: %v", err)
+	}
+	var handshakeErr *HandshakeError
+	if err := handshake(t, "tls-version-13", server, utils.TLS{CAFile: caFile, MinVersion: "1.3"}); !errors.As(err, &handshakeErr) {
+		t.Errorf("handshake with minVersion 1.3 to a TLS 1.2 server = %v, want a HandshakeError", err)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		config     utils.TLS
+		minVersion uint16
+		wantErr    bool
+	}{
+		{"default", utils.TLS{}, tls.VersionTLS12, false},
+		{"tls 1.0", utils.TLS{MinVersion: "1.0"}, tls.VersionTLS10, false},
+		{"tls 1.1", utils.TLS{MinVersion: "1.1"}, tls.VersionTLS11, false},
+		{"tls 1.2", utils.TLS{MinVersion: "1.2"}, tls.VersionTLS12, false},
+		{"tls 1.3", utils.TLS{MinVersion: "1.3"}, tls.VersionTLS13, false},
+		{"unknown version", utils.TLS{MinVersion: "1.4"}, 0, true},
+		{"prefixed version", utils.TLS{MinVersion: "TLS1.2"}, 0, true},
+		{"missing ca file", utils.TLS{CAFile: filepath.Join(dir, "missing.pem")}, 0, true},
+		{"ca file without certificate", utils.TLS{CAFile: notPEM}, 0, true},
+		{"fingerprint not in hex", utils.TLS{Fingerprint: "not-hex"}, 0, true},
+		{"fingerprint too short", utils.TLS{Fingerprint: "abcd"}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := newTLSConfig(test.config)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newTLSConfig() error = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil && config.MinVersion != test.minVersion {
+				t.Errorf("MinVersion = %x, want %x", config.MinVersion, test.minVersion)
+			}
+		})
+	}
+}
+
+// colons Returns the hex fingerprint with its bytes separated by colons, as printed by openssl
+func colons(fingerprint string) string {
+	var parts []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		parts = append(parts, fingerprint[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,273 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"powerstore-metrics-exporter/utils"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// recordedHeaders The response headers kept in the recordings, the others are dropped as they may carry the token or cookie
+var recordedHeaders = []string{"Content-Type", "Content-Range", "Retry-After"}
+
+// exchange A request to the powerstore and its response, the content of a recording file
+type exchange struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+// recordedRequest The method, path with query and body of a request, without its headers
+type recordedRequest struct {
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+	Body   string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body"`
+}
+
+// newTrafficTransport Returns the transport recording the requests sent by next or replaying them as set by traffic,
+// next itself when neither is set
+func newTrafficTransport(traffic utils.Traffic, ip string, next http.RoundTripper, logger log.Logger) (http.RoundTripper, error) {
+	switch {
+	case traffic.Record != "" && traffic.Replay != "":
+		return nil, errors.New("the powerstore requests cannot be recorded and replayed at once")
+	case traffic.Record != "":
+		dir := trafficDir(traffic.Record, ip)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create the record directory: %w", err)
+		}
+		return &recordTransport{next: next, dir: dir, logger: logger, counts: make(map[string]int)}, nil
+	case traffic.Replay != "":
+		return newReplayTransport(trafficDir(traffic.Replay, ip))
+	}
+	return next, nil
+}
+
+// trafficDir The directory of the recordings of a powerstore, named after its ip
+func trafficDir(dir, ip string) string {
+	return filepath.Join(dir, strings.NewReplacer(":", "_", "/", "_").Replace(ip))
+}
+
+// requestKey The key matching a replayed request to its recordings
+func requestKey(method, uri, body string) string {
+	return method + " " + uri + "\n" + body
+}
+
+// requestBody Returns the body of the request without consuming it
+func requestBody(request *http.Request) (string, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return "", nil
+	}
+	if request.GetBody == nil {
+		return "", errors.New("the body of the request cannot be read twice")
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	return string(content), err
+}
+
+// recordTransport Writes every request sent to the powerstore and its response to a file of dir,
+// the credentials, token and cookie are not written
+type recordTransport struct {
+	next   http.RoundTripper
+	dir    string
+	logger log.Logger
+	lock   sync.Mutex
+	counts map[string]int
+}
+
+func (t *recordTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := requestBody(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	recorded := exchange{
+		Request: recordedRequest{Method: request.Method, URI: request.URL.RequestURI(), Body: body},
+		Response: recordedResponse{
+			Status: response.StatusCode,
+			Header: make(map[string]string),
+			Body:   string(respBody),
+		},
+	}
+	for _, name := range recordedHeaders {
+		if value := response.Header.Get(name); value != "" {
+			recorded.Response.Header[name] = value
+		}
+	}
+	// the session of login_session is only identified by its headers, its body still holds the session id and user
+	if strings.HasSuffix(request.URL.Path, "/login_session") {
+		recorded.Response.Body = "[]"
+	}
+	if err := t.write(recorded); err != nil {
+		level.Warn(t.logger).Log("msg", "record powerstore request error", "err", err, "uri", recorded.Request.URI)
+	}
+	return response, nil
+}
+
+// write Write the exchange to a new file, named after its endpoint, the hash of its request and its rank among the same requests
+func (t *recordTransport) write(recorded exchange) error {
+	key := requestKey(recorded.Request.Method, recorded.Request.URI, recorded.Request.Body)
+	t.lock.Lock()
+	rank := t.counts[key]
+	t.counts[key]++
+	t.lock.Unlock()
+
+	sum := sha256.Sum256([]byte(key))
+	endpoint := endpointOf(strings.TrimPrefix(recorded.Request.URI, "/api/rest/"), recorded.Request.Body)
+	name := fmt.Sprintf("%s_%s_%s_%04d.json", recorded.Request.Method,
+		strings.NewReplacer("/", "_", ":", "_").Replace(endpoint), hex.EncodeToString(sum[:4]), rank)
+	content, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.dir, name), content, 0o644)
+}
+
+// replayTransport Answers the requests with the recorded responses of the same requests, in the recorded order,
+// the last one is repeated once they are all served. No request reaches the powerstore.
+type replayTransport struct {
+	dir       string
+	responses map[string][]recordedResponse
+	lock      sync.Mutex
+	served    map[string]int
+}
+
+// newReplayTransport Load the recordings of dir, the files sorted by name keep the order the requests were recorded in
+func newReplayTransport(dir string) (*replayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recording to replay in %s", dir)
+	}
+	t := &replayTransport{
+		dir:       dir,
+		responses: make(map[string][]recordedResponse),
+		served:    make(map[string]int),
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var recorded exchange
+		if err := json.Unmarshal(content, &recorded); err != nil {
+			return nil, fmt.Errorf("decode the recording %s: %w", file, err)
+		}
+		key := requestKey(recorded.Request.Method, recorded.Request.URI, recorded.Request.Body)
+		t.responses[key] = append(t.responses[key], recorded.Response)
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := requestBody(request)
+	if request.Body != nil {
+		request.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	uri := request.URL.RequestURI()
+	recorded, ok := t.next(requestKey(request.Method, uri, body))
+	if !ok {
+		recorded = unrecordedResponse(request.URL.Path, request.Method+" "+uri+" is not recorded in "+t.dir)
+	}
+	header := make(http.Header)
+	for name, value := range recorded.Header {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}, nil
+}
+
+// next Returns the next recorded response of the request
+func (t *replayTransport) next(key string) (recordedResponse, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	responses := t.responses[key]
+	if len(responses) == 0 {
+		return recordedResponse{}, false
+	}
+	rank := t.served[key]
+	if rank < len(responses)-1 {
+		t.served[key]++
+	}
+	return responses[rank], true
+}
+
+// unrecordedResponse The response of a request without recording: the login and logout succeed, so that recordings
+// made after the login still replay, the other requests get a 404 with the error body of the powerstore
+func unrecordedResponse(path, message string) recordedResponse {
+	switch {
+	case strings.HasSuffix(path, "/login_session"):
+		return recordedResponse{Status: http.StatusOK, Body: "[]"}
+	case strings.HasSuffix(path, "/logout"):
+		return recordedResponse{Status: http.StatusNoContent}
+	}
+	content, _ := json.Marshal(map[string]interface{}{
+		"messages": []map[string]string{{"severity": "Error", "message_l10n": message}},
+	})
+	return recordedResponse{
+		Status: http.StatusNotFound,
+		Header: map[string]string{"Content-Type": "application/json"},
+		Body:   string(content),
+	}
+}
@@ -17,13 +17,13 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
 var metricApplianceDescMap = map[string]string{
@@ -31,40 +31,48 @@ var metricApplianceDescMap = map[string]string{
 }
 
 type applianceCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewApplianceCollector(api *client.Client, logger log.Logger) *applianceCollector {
-	metrics := getApplianceMetrics(api.IP)
+func NewApplianceCollector(api client.PowerStoreAPI, logger log.Logger) *applianceCollector {
+	metrics := getApplianceMetrics(api.Address())
 	return &applianceCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("appliance", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *applianceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *applianceCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting appliance data")
 	startTime := time.Now()
-	applianceData, err := c.client.GetAppliance()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	applianceData, err := c.client.GetAppliance(ctx)
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "get appliance data error", "err", err)
+		scrapeErr.set(err)
 		return
 	}
-	for _, appliance := range gjson.Parse(applianceData).Array() {
-		tag := appliance.Get("service_tag")
-		applianceID := appliance.Get("id").String()
+	for _, appliance := range applianceData {
 		metricDesc := c.metrics["tag"]
-		if tag.Exists() && tag.Type != gjson.Null {
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, 0, tag.String(), applianceID)
+		if appliance.ServiceTag != nil {
+			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, 0, *appliance.ServiceTag, appliance.ID)
 		}
 	}
 	level.Info(c.logger).Log("msg", "Obtaining the appliance is successful", "time", time.Since(startTime))
 }
 
 func (c *applianceCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -17,36 +17,37 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var capCollectorMetric = []string{
-	"last_logical_provisioned",
-	"last_logical_used",
-	"last_physical_total",
-	"last_physical_used",
-	"max_logical_provisioned",
-	"max_logical_used",
-	"max_physical_total",
-	"max_physical_used",
-	"last_data_physical_used",
-	"max_data_physical_used",
-	"last_efficiency_ratio",
-	"last_data_reduction",
-	"last_snapshot_savings",
-	"last_thin_savings",
-	"max_efficiency_ratio",
-	"max_data_reduction",
-	"max_snapshot_savings",
-	"max_thin_savings",
-	"last_shared_logical_used",
-	"max_shared_logical_used",
+var capCollectorMetric = []fieldMetric[client.ApplianceSpace]{
+	{"last_logical_provisioned", func(s client.ApplianceSpace) *float64 { return s.LastLogicalProvisioned }},
+	{"last_logical_used", func(s client.ApplianceSpace) *float64 { return s.LastLogicalUsed }},
+	{"last_physical_total", func(s client.ApplianceSpace) *float64 { return s.LastPhysicalTotal }},
+	{"last_physical_used", func(s client.ApplianceSpace) *float64 { return s.LastPhysicalUsed }},
+	{"max_logical_provisioned", func(s client.ApplianceSpace) *float64 { return s.MaxLogicalProvisioned }},
+	{"max_logical_used", func(s client.ApplianceSpace) *float64 { return s.MaxLogicalUsed }},
+	{"max_physical_total", func(s client.ApplianceSpace) *float64 { return s.MaxPhysicalTotal }},
+	{"max_physical_used", func(s client.ApplianceSpace) *float64 { return s.MaxPhysicalUsed }},
+	{"last_data_physical_used", func(s client.ApplianceSpace) *float64 { return s.LastDataPhysicalUsed }},
+	{"max_data_physical_used", func(s client.ApplianceSpace) *float64 { return s.MaxDataPhysicalUsed }},
+	{"last_efficiency_ratio", func(s client.ApplianceSpace) *float64 { return s.LastEfficiencyRatio }},
+	{"last_data_reduction", func(s client.ApplianceSpace) *float64 { return s.LastDataReduction }},
+	{"last_snapshot_savings", func(s client.ApplianceSpace) *float64 { return s.LastSnapshotSavings }},
+	{"last_thin_savings", func(s client.ApplianceSpace) *float64 { return s.LastThinSavings }},
+	{"max_efficiency_ratio", func(s client.ApplianceSpace) *float64 { return s.MaxEfficiencyRatio }},
+	{"max_data_reduction", func(s client.ApplianceSpace) *float64 { return s.MaxDataReduction }},
+	{"max_snapshot_savings", func(s client.ApplianceSpace) *float64 { return s.MaxSnapshotSavings }},
+	{"max_thin_savings", func(s client.ApplianceSpace) *float64 { return s.MaxThinSavings }},
+	{"last_shared_logical_used", func(s client.ApplianceSpace) *float64 { return s.LastSharedLogicalUsed }},
+	{"max_shared_logical_used", func(s client.ApplianceSpace) *float64 { return s.MaxSharedLogicalUsed }},
 }
 
 var metricCapDescMap = map[string]string{
@@ -73,38 +74,54 @@ var metricCapDescMap = map[string]string{
 }
 
 type capacityCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewCapacityCollector(api *client.Client, logger log.Logger) *capacityCollector {
-	metrics := getCapacityMetrics(api.IP)
+func NewCapacityCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *capacityCollector {
+	metrics := getCapacityMetrics(api.Address())
 	return &capacityCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("capacity", api.Address()),
+		samples:  newSampleMetrics("capacity", api.Address(), config),
+		interval: intervalOf(config, "capacity"),
+		logger:   logger,
 	}
 }
 
 func (c *capacityCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *capacityCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting capacity data")
 	startTime := time.Now()
-	applianceArray := client.PowerstoreModuleID[c.client.IP]
-	for applianceID, _ := range applianceArray["appliance"] {
-		capacityData, err := c.client.GetCap(applianceID)
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
+	for applianceID := range c.client.Inventory().Get(client.ModuleAppliance) {
+		capacityData, err := c.client.GetCap(ctx, applianceID, c.interval)
 		if err != nil {
 			level.Warn(c.logger).Log("msg", "get capacity data error", "err", err)
+			scrapeErr.set(err)
 			return
 		}
-		capacityDataArray := gjson.Parse(capacityData).Array()
-		capacity := capacityDataArray[len(capacityDataArray)-1]
-		name := capacity.Get("appliance_id").String()
-		for _, metricName := range capCollectorMetric {
-			metricValue := capacity.Get(metricName)
-			metricDesc := c.metrics[metricName]
-			if metricValue.Exists() && metricValue.Type != gjson.Null {
-				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), name)
+		if len(capacityData) == 0 {
+			level.Warn(c.logger).Log("msg", "get capacity data is null")
+			continue
+		}
+		for _, capacity := range samplePoints(samples, applianceID, capacityData) {
+			for _, metric := range capCollectorMetric {
+				metricDesc := c.metrics[metric.name]
+				if metricValue := metric.value(capacity.sample); metricValue != nil {
+					samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, capacity.sample.ApplianceID), capacity.pointTime)
+				}
 			}
 		}
 	}
@@ -112,6 +129,8 @@ func (c *capacityCollector) Collect(ch chan<- prometheus.Metric) {
 }
 
 func (c *capacityCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -119,10 +138,10 @@ func (c *capacityCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getCapacityMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range capCollectorMetric {
-		res[metricName] = prometheus.NewDesc(
-			"powerstore_cap_"+metricName,
-			getCapacityDescByType(metricName),
+	for _, metric := range capCollectorMetric {
+		res[metric.name] = newSampleDesc(
+			"powerstore_cap_"+metric.name,
+			getCapacityDescByType(metric.name),
 			[]string{"appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -17,13 +17,13 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
 var statuMetricsMap = map[string]map[string]int{
@@ -35,55 +35,55 @@ var metricClusterDescMap = map[string]string{
 }
 
 type clusterCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewClusterCollector(api *client.Client, logger log.Logger) *clusterCollector {
-	metrics := getClusterMetrics(api.IP)
+func NewClusterCollector(api client.PowerStoreAPI, logger log.Logger) *clusterCollector {
+	metrics := getClusterMetrics(api.Address())
 	return &clusterCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("cluster", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *clusterCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting cluster data")
 	startTime := time.Now()
-	clusterData, err := c.client.GetCluster()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	clusterData, err := c.client.GetCluster(ctx)
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "get cluster data error", "err", err)
+		scrapeErr.set(err)
 		return
 	}
-	for _, cluster := range gjson.Parse(clusterData).Array() {
-		stateValue := getFloatData("cluster_state", cluster.Get("state"))
-		id := cluster.Get("master_appliance_id").String()
-		clusterName := cluster.Get("name").String()
-		clusterId := cluster.Get("global_id").String()
-		clusterIp := cluster.Get("management_address").String()
-		metricDesc := c.metrics["cluster"]
-		if cluster.Exists() && cluster.Type != gjson.Null {
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, stateValue, id, clusterId, clusterIp, clusterName)
+	for _, cluster := range clusterData {
+		stateValue, err := getFloatData("cluster_state", cluster.State)
+		if err != nil {
+			level.Error(c.logger).Log("msg", "parse cluster data error", "err", err, "cluster", cluster.Name)
+			continue
 		}
+		metricDesc := c.metrics["cluster"]
+		ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, stateValue, cluster.MasterApplianceID, cluster.GlobalID, cluster.ManagementAddress, cluster.Name)
 	}
 	level.Info(c.logger).Log("msg", "Obtaining the cluster info is successful", "time", time.Since(startTime))
 }
 
-func getFloatData(key string, value gjson.Result) float64 {
-	if v, ok := statuMetricsMap[key]; ok {
-		if res, ok2 := v[value.String()]; ok2 {
-			return float64(res)
-		} else {
-			return float64(v["other"])
-		}
-	} else {
-		return value.Float()
-	}
+func getFloatData(key string, value string) (float64, error) {
+	return parseValue(statuMetricsMap, key, value)
 }
 
 func (c *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
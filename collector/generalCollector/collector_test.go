@@ -0,0 +1,123 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"context"
+	"errors"
+	"powerstore-metrics-exporter/collector/client"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// stubAPI A powerstore answering the calls of the collectors from fixed data, the calls it does not override panic
+type stubAPI struct {
+	client.PowerStoreAPI
+	clusters []client.Cluster
+	ports    map[string][]client.Port
+	err      error
+}
+
+func (s *stubAPI) Address() string {
+	return "192.0.2.1"
+}
+
+func (s *stubAPI) GetCluster(ctx context.Context) ([]client.Cluster, error) {
+	return s.clusters, s.err
+}
+
+func (s *stubAPI) GetPort(ctx context.Context, portType string) ([]client.Port, error) {
+	return s.ports[portType], s.err
+}
+
+func TestClusterCollector(t *testing.T) {
+	api := &stubAPI{clusters: []client.Cluster{
+		{ID: "0", GlobalID: "PS0001", Name: "configured", ManagementAddress: "192.0.2.10", MasterApplianceID: "A1", State: "Configured"},
+		{ID: "1", GlobalID: "PS0002", Name: "unconfigured", ManagementAddress: "192.0.2.20", MasterApplianceID: "A2", State: "Unconfigured"},
+	}}
+	expected := `
+# HELP powerstore_cluster cluster state ,1 is Configured,0 other
+# TYPE powerstore_cluster gauge
+powerstore_cluster{IP="192.0.2.1",global_id="PS0001",management_address="192.0.2.10",master_appliance_id="A1",name="configured"} 1
+powerstore_cluster{IP="192.0.2.1",global_id="PS0002",management_address="192.0.2.20",master_appliance_id="A2",name="unconfigured"} 0
+# HELP powerstore_scrape_collector_success Whether the collector got all its data from the powerstore,1 is success,0 is failure
+# TYPE powerstore_scrape_collector_success gauge
+powerstore_scrape_collector_success{IP="192.0.2.1",collector="cluster"} 1
+`
+	collector := NewClusterCollector(api, log.NewNopLogger())
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"powerstore_cluster", "powerstore_scrape_collector_success"); err != nil {
+		t.Error(err)
+	}
+
+	api.err = errors.New("the powerstore answered 500")
+	expected = `
+# HELP powerstore_scrape_collector_success Whether the collector got all its data from the powerstore,1 is success,0 is failure
+# TYPE powerstore_scrape_collector_success gauge
+powerstore_scrape_collector_success{IP="192.0.2.1",collector="cluster"} 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"powerstore_cluster", "powerstore_scrape_collector_success"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPortCollector(t *testing.T) {
+	up, down := true, false
+	gbps, mbps, auto := "25_Gbps", "100_Mbps", "Auto"
+	api := &stubAPI{ports: map[string][]client.Port{
+		"eth_port": {
+			{ID: "e0", Name: "eth-0", ApplianceID: "A1", IsLinkUp: &up, CurrentSpeed: &gbps},
+			{ID: "e1", Name: "eth-1", ApplianceID: "A1", IsLinkUp: &down, CurrentSpeed: &auto},
+			{ID: "e2", Name: "eth-2", ApplianceID: "A1", IsLinkUp: &up, CurrentSpeed: &mbps},
+		},
+		"fc_port": {
+			{ID: "f0", Name: "fc-0", ApplianceID: "A1"},
+		},
+	}}
+	expected := `
+# HELP powerstore_eth_port_current_speed Supported Ethernet front-end port transmission speeds,units is Gps
+# TYPE powerstore_eth_port_current_speed gauge
+powerstore_eth_port_current_speed{IP="192.0.2.1",appliance_id="A1",eth_port_id="eth-0"} 25
+powerstore_eth_port_current_speed{IP="192.0.2.1",appliance_id="A1",eth_port_id="eth-2"} 100
+# HELP powerstore_eth_port_is_link_up Indicates whether the port's link is up:true is 1,false is 0
+# TYPE powerstore_eth_port_is_link_up gauge
+powerstore_eth_port_is_link_up{IP="192.0.2.1",appliance_id="A1",eth_port_id="eth-0"} 1
+powerstore_eth_port_is_link_up{IP="192.0.2.1",appliance_id="A1",eth_port_id="eth-1"} 0
+powerstore_eth_port_is_link_up{IP="192.0.2.1",appliance_id="A1",eth_port_id="eth-2"} 1
+# HELP powerstore_fc_port_current_speed Supported Ethernet front-end port transmission speeds,units is Gps
+# TYPE powerstore_fc_port_current_speed gauge
+powerstore_fc_port_current_speed{IP="192.0.2.1",appliance_id="A1",fc_port_id="fc-0"} 0
+# HELP powerstore_fc_port_is_link_up Indicates whether the port's link is up:true is 1,false is 0
+# TYPE powerstore_fc_port_is_link_up gauge
+powerstore_fc_port_is_link_up{IP="192.0.2.1",appliance_id="A1",fc_port_id="fc-0"} 0
+# HELP powerstore_scrape_collector_success Whether the collector got all its data from the powerstore,1 is success,0 is failure
+# TYPE powerstore_scrape_collector_success gauge
+powerstore_scrape_collector_success{IP="192.0.2.1",collector="port"} 1
+`
+	// the speed of eth-1 is not a number, its current_speed series is skipped, fc-0 has no speed and reports 0
+	collector := NewPortCollector(api, log.NewNopLogger())
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"powerstore_eth_port_current_speed", "powerstore_eth_port_is_link_up",
+		"powerstore_fc_port_current_speed", "powerstore_fc_port_is_link_up",
+		"powerstore_scrape_collector_success"); err != nil {
+		t.Error(err)
+	}
+}
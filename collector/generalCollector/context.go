@@ -0,0 +1,47 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ContextCollector A collector whose powerstore requests are cancelled with the context of the collection,
+// Collect uses a context that is never cancelled
+type ContextCollector interface {
+	prometheus.Collector
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// WithContext Returns the collector collecting with ctx, e.g. the context of a scrape request
+func WithContext(ctx context.Context, collector prometheus.Collector) prometheus.Collector {
+	if c, ok := collector.(ContextCollector); ok {
+		return contextCollector{ContextCollector: c, ctx: ctx}
+	}
+	return collector
+}
+
+type contextCollector struct {
+	ContextCollector
+	ctx context.Context
+}
+
+func (c contextCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(c.ctx, ch)
+}
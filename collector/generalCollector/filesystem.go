@@ -17,19 +17,20 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var metricFileSystemCollector = []string{
-	"logical_provisioned",
-	"logical_used",
-	"thin_savings",
+var metricFileSystemCollector = []fieldMetric[client.FilesystemSpace]{
+	{"logical_provisioned", func(s client.FilesystemSpace) *float64 { return s.LogicalProvisioned }},
+	{"logical_used", func(s client.FilesystemSpace) *float64 { return s.LogicalUsed }},
+	{"thin_savings", func(s client.FilesystemSpace) *float64 { return s.ThinSavings }},
 }
 
 // file description
@@ -40,41 +41,54 @@ var metricFileSystemDescMap = map[string]string{
 }
 
 type fileSystemCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewFileCollector(api *client.Client, logger log.Logger) *fileSystemCollector {
-	metrics := getFileSystemMetrics(api.IP)
+func NewFileCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *fileSystemCollector {
+	metrics := getFileSystemMetrics(api.Address())
 	return &fileSystemCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("file", api.Address()),
+		samples:  newSampleMetrics("file", api.Address(), config),
+		interval: intervalOf(config, "file"),
+		logger:   logger,
 	}
 }
 
 func (c *fileSystemCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *fileSystemCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting filesystem data")
 	startTime := time.Now()
-	moduleIDArray := client.PowerstoreModuleID[c.client.IP]
-	for filesystemID, filesystemName := range moduleIDArray["filesystem"] {
-		filesystemData, err := c.client.GetFilesystemCap(filesystemID)
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
+	for filesystemID, filesystem := range c.client.Inventory().Get(client.ModuleFilesystem) {
+		filesystemData, err := c.client.GetFilesystemCap(ctx, filesystemID, c.interval)
 		if err != nil {
 			level.Warn(c.logger).Log("msg", "get filesystem data error", "err", err)
+			scrapeErr.set(err)
 			return
 		}
-		filesystemArray := gjson.Parse(filesystemData).Array()
-		if len(filesystemArray) == 0 {
+		if len(filesystemData) == 0 {
 			continue
 		}
 
-		id := filesystemArray[len(filesystemArray)-1].Get("appliance_id").String()
-		for _, metricName := range metricFileSystemCollector {
-			metricValue := filesystemArray[len(filesystemArray)-1].Get(metricName)
-			metricDesc := c.metrics["filesystem_"+metricName]
-			if metricValue.Exists() && metricValue.Type != gjson.Null {
-				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), filesystemName.String(), id)
+		for _, sample := range samplePoints(samples, filesystemID, filesystemData) {
+			for _, metric := range metricFileSystemCollector {
+				metricDesc := c.metrics["filesystem_"+metric.name]
+				if metricValue := metric.value(sample.sample); metricValue != nil {
+					samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, filesystem.Name, sample.sample.ApplianceID), sample.pointTime)
+				}
 			}
 		}
 	}
@@ -82,6 +96,8 @@ func (c *fileSystemCollector) Collect(ch chan<- prometheus.Metric) {
 }
 
 func (c *fileSystemCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -89,10 +105,10 @@ func (c *fileSystemCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getFileSystemMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range metricFileSystemCollector {
-		res["filesystem_"+metricName] = prometheus.NewDesc(
-			"powerstore_filesystem_"+metricName,
-			getFileSystemDescByType(metricName),
+	for _, metric := range metricFileSystemCollector {
+		res["filesystem_"+metric.name] = newSampleDesc(
+			"powerstore_filesystem_"+metric.name,
+			getFileSystemDescByType(metric.name),
 			[]string{"name", "appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -17,13 +17,13 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
 var hardwareCollectorType = []string{
@@ -39,85 +39,84 @@ var metricHardwareDescMap = map[string]string{
 }
 
 var metricHardwareValueMap = map[string]map[string]int{
-	"lifecycle_state": {"Healthy": 1, "others": 0},
+	"lifecycle_state": {"Healthy": 1, "other": 0},
 }
 
 type hardwareCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewHardwareCollector(api *client.Client, logger log.Logger) *hardwareCollector {
-	metrics := getHardwareMetrics(api.IP)
+func NewHardwareCollector(api client.PowerStoreAPI, logger log.Logger) *hardwareCollector {
+	metrics := getHardwareMetrics(api.Address())
 	return &hardwareCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("hardware", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *hardwareCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *hardwareCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting hardware data")
 	startTime := time.Now()
-	nodeData, err := c.client.GetHardware("Node")
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	nodeData, err := c.client.GetHardware(ctx, "Node")
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "get node data error", "err", err)
+		scrapeErr.set(err)
 		return
 	}
-	for _, node := range gjson.Parse(nodeData).Array() {
-		id := node.Get("appliance_id").String()
-		nodeName := node.Get("name").String()
-		sn := node.Get("serial_number").String()
-		state := node.Get("lifecycle_state").String()
-		if node.Exists() && node.Type != gjson.Null {
-			metricDesc := c.metrics["node"+id]
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, 0, nodeName, sn, state, id)
+	for _, node := range nodeData {
+		var state string
+		if node.LifecycleState != nil {
+			state = *node.LifecycleState
 		}
+		metricDesc := c.metrics["node"]
+		ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, 0, node.Name, node.SerialNumber, state, node.ApplianceID)
 	}
 	level.Info(c.logger).Log("msg", "Obtaining the node status is successful")
 	for _, types := range hardwareCollectorType {
-		hardwareData, err := c.client.GetHardware(types)
+		hardwareData, err := c.client.GetHardware(ctx, types)
 		if err != nil {
 			level.Warn(c.logger).Log("msg", "get hardware data error", "err", err)
+			scrapeErr.set(err)
 		}
-		for _, hardware := range gjson.Parse(hardwareData).Array() {
-			id := hardware.Get("appliance_id").String()
-			name := hardware.Get("name").String()
-			state := hardware.Get("lifecycle_state")
-			stateValue := getHardwareFloatDate("lifecycle_state", state)
-			if state.Exists() && state.Type != gjson.Null {
-				metricDesc := c.metrics[types+"state"]
-				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, stateValue, name, id)
-			}
-			if hardware.Get("type").String() == "Drive" {
-				size := hardware.Get("extra_details").Get("size")
-				if size.Exists() && size.Type != gjson.Null {
-					metricDesc := c.metrics["size"]
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, size.Float(), name, id, hardware.Get("extra_details").Get("drive_type").String())
+		for _, hardware := range hardwareData {
+			if hardware.LifecycleState != nil {
+				stateValue, err := getHardwareFloatDate("lifecycle_state", *hardware.LifecycleState)
+				if err != nil {
+					level.Error(c.logger).Log("msg", "parse hardware data error", "err", err, "hardware", hardware.Name)
+				} else {
+					metricDesc := c.metrics[types+"state"]
+					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, stateValue, hardware.Name, hardware.ApplianceID)
 				}
 			}
+			if hardware.Type == "Drive" && hardware.ExtraDetails.Size != nil {
+				metricDesc := c.metrics["size"]
+				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *hardware.ExtraDetails.Size, hardware.Name, hardware.ApplianceID, hardware.ExtraDetails.DriveType)
+			}
 		}
 	}
 	level.Info(c.logger).Log("msg", "Obtaining the hardware status is successful", "time", time.Since(startTime))
 }
 
 func (c *hardwareCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
 }
 
-func getHardwareFloatDate(key string, value gjson.Result) float64 {
-	if v, ok := metricHardwareValueMap[key]; ok {
-		if res, ok2 := v[value.String()]; ok2 {
-			return float64(res)
-		} else {
-			return float64(v["other"])
-		}
-	} else {
-		return value.Float()
-	}
+func getHardwareFloatDate(key string, value string) (float64, error) {
+	return parseValue(metricHardwareValueMap, key, value)
 }
 
 func getHardwareMetrics(ip string) map[string]*prometheus.Desc {
@@ -137,13 +136,11 @@ func getHardwareMetrics(ip string) map[string]*prometheus.Desc {
 			prometheus.Labels{"IP": ip})
 	}
 
-	for applianceID, _ := range client.PowerstoreModuleID[ip]["appliance"] {
-		res["node"+applianceID] = prometheus.NewDesc(
-			"powerstore_hardware_node_state",
-			getHardwareDescByType("lifecycle_state"),
-			[]string{"name", "serial_number", "state", "appliance_id"},
-			prometheus.Labels{"IP": ip})
-	}
+	res["node"] = prometheus.NewDesc(
+		"powerstore_hardware_node_state",
+		getHardwareDescByType("lifecycle_state"),
+		[]string{"name", "serial_number", "state", "appliance_id"},
+		prometheus.Labels{"IP": ip})
 
 	return res
 }
@@ -0,0 +1,67 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"fmt"
+	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
+)
+
+// collectorEntities The metrics/generate entity of the collectors whose interval can be configured
+var collectorEntities = map[string]string{
+	"metricAppliance":  client.EntityAppliancePerformance,
+	"metricNode":       client.EntityNodePerformance,
+	"metricVolume":     client.EntityVolumePerformance,
+	"metricVg":         client.EntityVgPerformance,
+	"metricFcPort":     client.EntityFcPortPerformance,
+	"metricEthPort":    client.EntityEthPortPerformance,
+	"metricNas":        client.EntityNasPerformance,
+	"metricFilesystem": client.EntityFilesystemPerformance,
+	"capacity":         client.EntityApplianceSpace,
+	"file":             client.EntityFilesystemSpace,
+	"wear":             client.EntityDriveWear,
+}
+
+// defaultIntervals The interval of the collectors when it is not configured, Five_Mins for the others
+var defaultIntervals = map[string]string{
+	"capacity": client.IntervalOneDay,
+}
+
+// CheckIntervals Returns an error when an interval of the config is set for an unknown collector or not supported by its entity
+func CheckIntervals(config utils.Metrics) error {
+	for collector, interval := range config.Intervals {
+		entity, ok := collectorEntities[collector]
+		if !ok {
+			return fmt.Errorf("unknown collector %q in metrics intervals", collector)
+		}
+		if err := client.CheckInterval(entity, interval); err != nil {
+			return fmt.Errorf("collector %s: %s", collector, err)
+		}
+	}
+	return nil
+}
+
+func intervalOf(config utils.Metrics, collector string) string {
+	if interval, ok := config.Intervals[collector]; ok {
+		return interval
+	}
+	if interval, ok := defaultIntervals[collector]; ok {
+		return interval
+	}
+	return client.IntervalFiveMins
+}
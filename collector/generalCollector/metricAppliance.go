@@ -17,31 +17,22 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var metricAppliancePerfCollectorMetric = []string{
-	"avg_read_latency",
-	"avg_latency",
-	"avg_write_latency",
-	"avg_read_iops",
-	"avg_read_bandwidth",
-	"avg_total_iops",
-	"avg_total_bandwidth",
-	"avg_write_iops",
-	"avg_write_bandwidth",
-	"avg_io_workload_cpu_utilization",
-	"avg_io_size",
-	"avg_read_size",
-	"avg_write_size",
-}
+var metricAppliancePerfCollectorMetric = append(
+	ioPerformanceMetrics(func(s client.AppliancePerformance) client.IoPerformance { return s.IoPerformance }),
+	fieldMetric[client.AppliancePerformance]{"avg_io_workload_cpu_utilization", func(s client.AppliancePerformance) *float64 { return s.AvgIoWorkloadCpuUtilization }},
+	fieldMetric[client.AppliancePerformance]{"avg_io_size", func(s client.AppliancePerformance) *float64 { return s.AvgIoSize }},
+)
 
 // performance description
 var metricAppliancePerfDescMap = map[string]string{
@@ -61,49 +52,69 @@ var metricAppliancePerfDescMap = map[string]string{
 }
 
 type metricApplianceCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricApplianceCollector(api *client.Client, logger log.Logger) *metricApplianceCollector {
-	metrics := getMetricApplianceMetrics(api.IP)
+func NewMetricApplianceCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricApplianceCollector {
+	metrics := getMetricApplianceMetrics(api.Address())
 	return &metricApplianceCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricAppliance", api.Address()),
+		samples:  newSampleMetrics("metricAppliance", api.Address(), config),
+		interval: intervalOf(config, "metricAppliance"),
+		logger:   logger,
 	}
 }
 
 func (c *metricApplianceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricApplianceCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting appliance performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	applianceArray := client.PowerstoreModuleID[c.client.IP]
-	for applianceID, applianceName := range applianceArray["appliance"] {
+	for applianceID, appliance := range c.client.Inventory().Get(client.ModuleAppliance) {
 		wg.Add(1)
 		go func(applianceID, applianceName string) {
 			defer wg.Done()
-			perfData, err := c.client.GetPerf(applianceID)
+			perfData, err := c.client.GetPerf(ctx, applianceID, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get appliance performance data error", "err", err)
+				scrapeErr.set(err)
+				return
+			}
+			if len(perfData) == 0 {
+				level.Warn(c.logger).Log("msg", "get appliance performance data is null")
+				return
 			}
-			appliancePerformanceArray := gjson.Parse(perfData).Array()
-			appliancePerformance := appliancePerformanceArray[len(appliancePerformanceArray)-1]
-			for _, metricName := range metricAppliancePerfCollectorMetric {
-				metricValue := appliancePerformance.Get(metricName)
-				metricDesc := c.metrics["appliance"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), applianceID, applianceName)
+			for _, appliancePerformance := range samplePoints(samples, applianceID, perfData) {
+				for _, metric := range metricAppliancePerfCollectorMetric {
+					metricDesc := c.metrics["appliance"+"_"+metric.name]
+					if metricValue := metric.value(appliancePerformance.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, applianceID, applianceName), appliancePerformance.pointTime)
+					}
 				}
 			}
-		}(applianceID, applianceName.String())
+		}(applianceID, appliance.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance appliance is successful", "time", time.Since(startTime))
 }
 
 func (c *metricApplianceCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -111,10 +122,10 @@ func (c *metricApplianceCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getMetricApplianceMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range metricAppliancePerfCollectorMetric {
-		res["appliance"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_perf_"+metricName,
-			getMetricApplianceDescByType(metricName),
+	for _, metric := range metricAppliancePerfCollectorMetric {
+		res["appliance"+"_"+metric.name] = newSampleDesc(
+			"powerstore_perf_"+metric.name,
+			getMetricApplianceDescByType(metric.name),
 			[]string{"appliance_id", "appliance_name"},
 			prometheus.Labels{"IP": ip})
 	}
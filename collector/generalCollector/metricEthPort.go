@@ -17,86 +17,101 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var metricEthPortCollectorMetric = []string{
-	"avg_bytes_rx_ps",
-	"avg_bytes_tx_ps",
-	"avg_pkt_rx_crc_error_ps",
-	"avg_pkt_rx_no_buffer_error_ps",
-	"avg_pkt_rx_ps",
-	"avg_pkt_tx_error_ps",
-	"avg_pkt_tx_ps",
+var metricEthPortCollectorMetric = []fieldMetric[client.EthPortPerformance]{
+	{"avg_bytes_rx_ps", func(s client.EthPortPerformance) *float64 { return s.AvgBytesRxPs }},
+	{"avg_bytes_tx_ps", func(s client.EthPortPerformance) *float64 { return s.AvgBytesTxPs }},
+	{"avg_pkt_rx_crc_error_ps", func(s client.EthPortPerformance) *float64 { return s.AvgPktRxCrcErrorPs }},
+	{"avg_pkt_rx_no_buffer_error_ps", func(s client.EthPortPerformance) *float64 { return s.AvgPktRxNoBufferErrorPs }},
+	{"avg_pkt_rx_ps", func(s client.EthPortPerformance) *float64 { return s.AvgPktRxPs }},
+	{"avg_pkt_tx_error_ps", func(s client.EthPortPerformance) *float64 { return s.AvgPktTxErrorPs }},
+	{"avg_pkt_tx_ps", func(s client.EthPortPerformance) *float64 { return s.AvgPktTxPs }},
 }
 
 var metricMetricEthPortDescMap = map[string]string{
-	"bytes_rx_ps":               "receive bytes in a second",
-	"bytes_tx_ps":               "send bytes in a second",
-	"pkt_rx_crc_error_ps":       "packet receive crc error in a second",
-	"pkt_rx_no_buffer_error_ps": "packet receive no buffer error in a second",
-	"pkt_rx_ps":                 "packet receive in a second",
-	"pkt_tx_error_ps":           "packet send error in a second",
-	"pkt_tx_ps":                 "packet get in a second",
+	"avg_bytes_rx_ps":               "receive bytes in a second",
+	"avg_bytes_tx_ps":               "send bytes in a second",
+	"avg_pkt_rx_crc_error_ps":       "packet receive crc error in a second",
+	"avg_pkt_rx_no_buffer_error_ps": "packet receive no buffer error in a second",
+	"avg_pkt_rx_ps":                 "packet receive in a second",
+	"avg_pkt_tx_error_ps":           "packet send error in a second",
+	"avg_pkt_tx_ps":                 "packet send in a second",
 }
 
 type metricEthPortCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricEthPortCollector(api *client.Client, logger log.Logger) *metricEthPortCollector {
-	metrics := getMetricEthPortfMetrics(api.IP)
+func NewMetricEthPortCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricEthPortCollector {
+	metrics := getMetricEthPortfMetrics(api.Address())
 	return &metricEthPortCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricEthPort", api.Address()),
+		samples:  newSampleMetrics("metricEthPort", api.Address(), config),
+		interval: intervalOf(config, "metricEthPort"),
+		logger:   logger,
 	}
 }
 
 func (c *metricEthPortCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricEthPortCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting ethPort performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	ethPortArray := client.PowerstoreModuleID[c.client.IP]
-	for portId, portName := range ethPortArray["ethport"] {
+	for portId, port := range c.client.Inventory().Get(client.ModuleEthPort) {
 		wg.Add(1)
 		go func(portId, portName string) {
 			defer wg.Done()
-			ethPortsData, err := c.client.GetMetricEthPort(portId)
+			ethPortsData, err := c.client.GetMetricEthPort(ctx, portId, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get ethPort performance data error", "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			ethPortDataArray := gjson.Parse(ethPortsData).Array()
-			if len(ethPortDataArray) == 0 {
+			if len(ethPortsData) == 0 {
 				level.Warn(c.logger).Log("msg", "get ethPort performance data is null")
 				return
 			}
-			ethPortData := ethPortDataArray[len(ethPortDataArray)-1]
-			applianceID := ethPortData.Get("appliance_id").String()
-			for _, metricName := range metricEthPortCollectorMetric {
-				metricValue := ethPortData.Get(metricName)
-				metricDesc := c.metrics["ethport"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), portName, applianceID)
+			for _, ethPortData := range samplePoints(samples, portId, ethPortsData) {
+				for _, metric := range metricEthPortCollectorMetric {
+					metricDesc := c.metrics["ethport"+"_"+metric.name]
+					if metricValue := metric.value(ethPortData.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, portName, ethPortData.sample.ApplianceID), ethPortData.pointTime)
+					}
 				}
 			}
-		}(portId, portName.String())
+		}(portId, port.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance ethPort is successful", "time", time.Since(startTime))
 }
 
 func (c *metricEthPortCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -104,10 +119,10 @@ func (c *metricEthPortCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getMetricEthPortfMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range metricEthPortCollectorMetric {
-		res["ethport"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_metricEthPort_"+metricName,
-			getMetricEthPortDescByType(metricName),
+	for _, metric := range metricEthPortCollectorMetric {
+		res["ethport"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricEthPort_"+metric.name,
+			getMetricEthPortDescByType(metric.name),
 			[]string{"eth_port_id", "appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
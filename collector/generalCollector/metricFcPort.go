@@ -17,29 +17,30 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var metricFcPortCollectorMetric = []string{
-	"avg_read_latency",
-	"avg_latency",
-	"avg_write_latency",
-	"avg_total_iops",
-	"avg_total_bandwidth",
-	"avg_dumped_frames_ps",
-	"avg_loss_of_signal_count_ps",
-	"avg_invalid_crc_count_ps",
-	"avg_loss_of_sync_count_ps",
-	"avg_invalid_tx_word_count_ps",
-	"avg_prim_seq_prot_err_count_ps",
-	"avg_link_failure_count_ps",
+var metricFcPortCollectorMetric = []fieldMetric[client.FcPortPerformance]{
+	{"avg_read_latency", func(s client.FcPortPerformance) *float64 { return s.AvgReadLatency }},
+	{"avg_latency", func(s client.FcPortPerformance) *float64 { return s.AvgLatency }},
+	{"avg_write_latency", func(s client.FcPortPerformance) *float64 { return s.AvgWriteLatency }},
+	{"avg_total_iops", func(s client.FcPortPerformance) *float64 { return s.AvgTotalIops }},
+	{"avg_total_bandwidth", func(s client.FcPortPerformance) *float64 { return s.AvgTotalBandwidth }},
+	{"avg_dumped_frames_ps", func(s client.FcPortPerformance) *float64 { return s.AvgDumpedFramesPs }},
+	{"avg_loss_of_signal_count_ps", func(s client.FcPortPerformance) *float64 { return s.AvgLossOfSignalCountPs }},
+	{"avg_invalid_crc_count_ps", func(s client.FcPortPerformance) *float64 { return s.AvgInvalidCrcCountPs }},
+	{"avg_loss_of_sync_count_ps", func(s client.FcPortPerformance) *float64 { return s.AvgLossOfSyncCountPs }},
+	{"avg_invalid_tx_word_count_ps", func(s client.FcPortPerformance) *float64 { return s.AvgInvalidTxWordCountPs }},
+	{"avg_prim_seq_prot_err_count_ps", func(s client.FcPortPerformance) *float64 { return s.AvgPrimSeqProtErrCountPs }},
+	{"avg_link_failure_count_ps", func(s client.FcPortPerformance) *float64 { return s.AvgLinkFailureCountPs }},
 }
 
 var metricMetricFcPortDescMap = map[string]string{
@@ -58,55 +59,69 @@ var metricMetricFcPortDescMap = map[string]string{
 }
 
 type metricFcPortCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricFcPortCollector(api *client.Client, logger log.Logger) *metricFcPortCollector {
-	metrics := getMetricFcPortMetrics(api.IP)
+func NewMetricFcPortCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricFcPortCollector {
+	metrics := getMetricFcPortMetrics(api.Address())
 	return &metricFcPortCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricFcPort", api.Address()),
+		samples:  newSampleMetrics("metricFcPort", api.Address(), config),
+		interval: intervalOf(config, "metricFcPort"),
+		logger:   logger,
 	}
 }
 
 func (c *metricFcPortCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricFcPortCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting fcPort performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	fcPortArray := client.PowerstoreModuleID[c.client.IP]
-	for portId, portName := range fcPortArray["fcport"] {
+	for portId, port := range c.client.Inventory().Get(client.ModuleFcPort) {
 		wg.Add(1)
 		go func(portId, portName string) {
 			defer wg.Done()
-			fcPortsData, err := c.client.GetMetricFcPort(portId)
+			fcPortsData, err := c.client.GetMetricFcPort(ctx, portId, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get fcPort performance data error", "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			fcPortDataArray := gjson.Parse(fcPortsData).Array()
-			if len(fcPortDataArray) == 0 {
+			if len(fcPortsData) == 0 {
 				level.Warn(c.logger).Log("msg", "get fcPort performance data is null")
 				return
 			}
-			fcPortData := fcPortDataArray[len(fcPortDataArray)-1]
-			applianceID := fcPortData.Get("appliance_id").String()
-			for _, metricName := range metricFcPortCollectorMetric {
-				metricValue := fcPortData.Get(metricName)
-				metricDesc := c.metrics["fcport"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), portName, applianceID)
+			for _, fcPortData := range samplePoints(samples, portId, fcPortsData) {
+				for _, metric := range metricFcPortCollectorMetric {
+					metricDesc := c.metrics["fcport"+"_"+metric.name]
+					if metricValue := metric.value(fcPortData.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, portName, fcPortData.sample.ApplianceID), fcPortData.pointTime)
+					}
 				}
 			}
-		}(portId, portName.String())
+		}(portId, port.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance fc port is successful", "time", time.Since(startTime))
 }
 
 func (c *metricFcPortCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -115,10 +130,10 @@ func (c *metricFcPortCollector) Describe(ch chan<- *prometheus.Desc) {
 func getMetricFcPortMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
 
-	for _, metricName := range metricFcPortCollectorMetric {
-		res["fcport"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_metricFcPort_"+metricName,
-			getMetricFcPortDescByType(metricName),
+	for _, metric := range metricFcPortCollectorMetric {
+		res["fcport"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricFcPort_"+metric.name,
+			getMetricFcPortDescByType(metric.name),
 			[]string{"fc_port_id", "appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -17,35 +17,26 @@
 package generalCollector
 
 import (
+	"context"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 )
 
-var metricFilesystemCollectorMetric = []string{
-	"avg_read_latency",
-	"avg_latency",
-	"avg_write_latency",
-	"avg_read_iops",
-	"avg_read_bandwidth",
-	"avg_total_iops",
-	"avg_total_bandwidth",
-	"avg_write_iops",
-	"avg_write_bandwidth",
-	"avg_size",
-	"avg_read_size",
-	"avg_write_size",
-	"avg_block_write_iops",
-	"avg_mirror_write_iops",
-	"avg_block_write_bandwidth",
-	"avg_mirror_write_bandwidth",
-	"avg_block_write_latency",
-	"avg_mirror_overhead_latency",
-}
+var metricFilesystemCollectorMetric = append(
+	ioPerformanceMetrics(func(s client.FilesystemPerformance) client.IoPerformance { return s.IoPerformance }),
+	fieldMetric[client.FilesystemPerformance]{"avg_size", func(s client.FilesystemPerformance) *float64 { return s.AvgSize }},
+	fieldMetric[client.FilesystemPerformance]{"avg_block_write_iops", func(s client.FilesystemPerformance) *float64 { return s.AvgBlockWriteIops }},
+	fieldMetric[client.FilesystemPerformance]{"avg_mirror_write_iops", func(s client.FilesystemPerformance) *float64 { return s.AvgMirrorWriteIops }},
+	fieldMetric[client.FilesystemPerformance]{"avg_block_write_bandwidth", func(s client.FilesystemPerformance) *float64 { return s.AvgBlockWriteBandwidth }},
+	fieldMetric[client.FilesystemPerformance]{"avg_mirror_write_bandwidth", func(s client.FilesystemPerformance) *float64 { return s.AvgMirrorWriteBandwidth }},
+	fieldMetric[client.FilesystemPerformance]{"avg_block_write_latency", func(s client.FilesystemPerformance) *float64 { return s.AvgBlockWriteLatency }},
+	fieldMetric[client.FilesystemPerformance]{"avg_mirror_overhead_latency", func(s client.FilesystemPerformance) *float64 { return s.AvgMirrorOverheadLatency }},
+)
 
 var metricMetricFilesystemDescMap = map[string]string{
 	"avg_read_latency":            "Average read latency in microseconds,unit is ms",
@@ -69,54 +60,69 @@ var metricMetricFilesystemDescMap = map[string]string{
 }
 
 type metricFilesystemCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricFilesystemCollector(api *client.Client, logger log.Logger) *metricFilesystemCollector {
-	metrics := getMetricFilesystemMetrics(api.IP)
+func NewMetricFilesystemCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricFilesystemCollector {
+	metrics := getMetricFilesystemMetrics(api.Address())
 	return &metricFilesystemCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricFilesystem", api.Address()),
+		samples:  newSampleMetrics("metricFilesystem", api.Address(), config),
+		interval: intervalOf(config, "metricFilesystem"),
+		logger:   logger,
 	}
 }
 
 func (c *metricFilesystemCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricFilesystemCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting filesystem performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	fileSystemArray := client.PowerstoreModuleID[c.client.IP]
-	for filesystemId, filesystemName := range fileSystemArray["filesystem"] {
+	for filesystemId, filesystem := range c.client.Inventory().Get(client.ModuleFilesystem) {
 		wg.Add(1)
 		go func(filesystemId, filesystemName string) {
 			defer wg.Done()
-			filesystemData, err := c.client.GetMetricsFilesystem(filesystemId)
+			filesystemData, err := c.client.GetMetricsFilesystem(ctx, filesystemId, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get filesystem performance data error", "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			filesystemArray := gjson.Parse(filesystemData).Array()
-			if len(filesystemArray) == 0 {
+			if len(filesystemData) == 0 {
 				level.Warn(c.logger).Log("msg", "get filesystem performance data is null")
 				return
 			}
-			applianceID := filesystemArray[len(filesystemArray)-1].Get("appliance_id").String()
-			for _, metricName := range metricFilesystemCollectorMetric {
-				metricValue := filesystemArray[len(filesystemArray)-1].Get(metricName)
-				metricDesc := c.metrics["filesystem"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), filesystemName, applianceID)
+			for _, sample := range samplePoints(samples, filesystemId, filesystemData) {
+				for _, metric := range metricFilesystemCollectorMetric {
+					metricDesc := c.metrics["filesystem"+"_"+metric.name]
+					if metricValue := metric.value(sample.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, filesystemName, sample.sample.ApplianceID), sample.pointTime)
+					}
 				}
 			}
-		}(filesystemId, filesystemName.String())
+		}(filesystemId, filesystem.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance filesystem is successful", "time", time.Since(startTime))
 }
 
 func (c *metricFilesystemCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -125,10 +131,10 @@ func (c *metricFilesystemCollector) Describe(ch chan<- *prometheus.Desc) {
 func getMetricFilesystemMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
 
-	for _, metricName := range metricFilesystemCollectorMetric {
-		res["filesystem"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_metricFilesystem_"+metricName,
-			getMetricFilesystemDescByType(metricName),
+	for _, metric := range metricFilesystemCollectorMetric {
+		res["filesystem"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricFilesystem_"+metric.name,
+			getMetricFilesystemDescByType(metric.name),
 			[]string{"name", "appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -136,7 +142,7 @@ func getMetricFilesystemMetrics(ip string) map[string]*prometheus.Desc {
 }
 
 func getMetricFilesystemDescByType(key string) string {
-	if v, ok := metricMetricFcPortDescMap[key]; ok {
+	if v, ok := metricMetricFilesystemDescMap[key]; ok {
 		return v
 	} else {
 		return key
@@ -17,29 +17,20 @@
 package generalCollector
 
 import (
+	"context"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 )
 
-var metricNasCollectorMetric = []string{
-	"avg_read_latency",
-	"avg_latency",
-	"avg_write_latency",
-	"avg_read_iops",
-	"avg_read_bandwidth",
-	"avg_total_iops",
-	"avg_total_bandwidth",
-	"avg_write_iops",
-	"avg_write_bandwidth",
-	"avg_size",
-	"avg_write_size",
-	"avg_read_size",
-}
+var metricNasCollectorMetric = append(
+	ioPerformanceMetrics(func(s client.NasPerformance) client.IoPerformance { return s.IoPerformance }),
+	fieldMetric[client.NasPerformance]{"avg_size", func(s client.NasPerformance) *float64 { return s.AvgSize }},
+)
 
 var metricMetricNasDescMap = map[string]string{
 	"avg_read_latency":    "Average read latency in microseconds,unit is ms",
@@ -57,55 +48,70 @@ var metricMetricNasDescMap = map[string]string{
 }
 
 type metricNasCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricNasCollector(api *client.Client, logger log.Logger) *metricNasCollector {
-	metrics := getMetricNasMetrics(api.IP)
+func NewMetricNasCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricNasCollector {
+	metrics := getMetricNasMetrics(api.Address())
 	return &metricNasCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricNas", api.Address()),
+		samples:  newSampleMetrics("metricNas", api.Address(), config),
+		interval: intervalOf(config, "metricNas"),
+		logger:   logger,
 	}
 }
 
 func (c *metricNasCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricNasCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting nas server performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	vgArray := client.PowerstoreModuleID[c.client.IP]
-	for nasId, nasName := range vgArray["nas"] {
+	for nasId, nas := range c.client.Inventory().Get(client.ModuleNas) {
 		wg.Add(1)
 		go func(nasId, nasName string) {
 			defer wg.Done()
-			metricNasData, err := c.client.GetMetricByNas(nasId)
+			metricNasData, err := c.client.GetMetricByNas(ctx, nasId, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get nas server performance data error", "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			nasDataArray := gjson.Parse(metricNasData).Array()
-			if len(nasDataArray) == 0 {
+			if len(metricNasData) == 0 {
 				level.Warn(c.logger).Log("msg", "get nas server performance data is null")
 				return
 			}
 
-			nasData := nasDataArray[len(nasDataArray)-1]
-			for _, metricName := range metricVgCollectorMetric {
-				metricValue := nasData.Get(metricName)
-				metricDesc := c.metrics["nas"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), nasName)
+			for _, nasData := range samplePoints(samples, nasId, metricNasData) {
+				for _, metric := range metricNasCollectorMetric {
+					metricDesc := c.metrics["nas"+"_"+metric.name]
+					if metricValue := metric.value(nasData.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, nasName), nasData.pointTime)
+					}
 				}
 			}
-		}(nasId, nasName.String())
+		}(nasId, nas.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance nas server is successful", "time", time.Since(startTime))
 }
 
 func (c *metricNasCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -113,10 +119,10 @@ func (c *metricNasCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getMetricNasMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range metricVgCollectorMetric {
-		res["nas"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_metricNas_"+metricName,
-			getMetricNasDescByType(metricName),
+	for _, metric := range metricNasCollectorMetric {
+		res["nas"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricNas_"+metric.name,
+			getMetricNasDescByType(metric.name),
 			[]string{"nas_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -124,7 +130,7 @@ func getMetricNasMetrics(ip string) map[string]*prometheus.Desc {
 }
 
 func getMetricNasDescByType(key string) string {
-	if v, ok := metricMetricVgDescMap[key]; ok {
+	if v, ok := metricMetricNasDescMap[key]; ok {
 		return v
 	} else {
 		return key
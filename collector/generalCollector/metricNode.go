@@ -0,0 +1,149 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"context"
+	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricNodeCollectorMetric = append(
+	ioPerformanceMetrics(func(s client.NodePerformance) client.IoPerformance { return s.IoPerformance }),
+	fieldMetric[client.NodePerformance]{"avg_io_workload_cpu_utilization", func(s client.NodePerformance) *float64 { return s.AvgIoWorkloadCpuUtilization }},
+	fieldMetric[client.NodePerformance]{"avg_io_size", func(s client.NodePerformance) *float64 { return s.AvgIoSize }},
+	fieldMetric[client.NodePerformance]{"avg_current_logins", func(s client.NodePerformance) *float64 { return s.AvgCurrentLogins }},
+)
+
+// performance description
+var metricNodeDescMap = map[string]string{
+	"avg_read_latency":                "Average read latency in microseconds,unit is ms",
+	"avg_latency":                     "Average read and write latency in microseconds,unit is ms",
+	"avg_write_latency":               "Average write latency in microseconds,unit is ms",
+	"avg_read_iops":                   "Total read operations per second,unit is iops",
+	"avg_read_bandwidth":              "Read rate in bytes per second,unit is bps",
+	"avg_total_iops":                  "Total read and write operations per second,unit is iops",
+	"avg_total_bandwidth":             "Total data transfer rate in bytes per second,unit is bps",
+	"avg_write_iops":                  "Total write operations per second,unit is iops",
+	"avg_write_bandwidth":             "Write rate in bytes per second,unit is bps",
+	"avg_io_workload_cpu_utilization": "The percentage of CPU Utilization on the cores of the node dedicated to servicing storage I/O requests.unit is %",
+	"avg_io_size":                     "Average size of read and write operations in bytes.unit is bytes",
+	"avg_write_size":                  "Average write size in bytes.unit is bytes",
+	"avg_read_size":                   "Average read size in bytes.unit is bytes",
+	"avg_current_logins":              "Average number of logins to the node from the hosts",
+}
+
+type metricNodeCollector struct {
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
+}
+
+func NewMetricNodeCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricNodeCollector {
+	metrics := getMetricNodeMetrics(api.Address())
+	return &metricNodeCollector{
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricNode", api.Address()),
+		samples:  newSampleMetrics("metricNode", api.Address(), config),
+		interval: intervalOf(config, "metricNode"),
+		logger:   logger,
+	}
+}
+
+func (c *metricNodeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricNodeCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	level.Info(c.logger).Log("msg", "Start collecting node performance data")
+	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	nodes, err := c.client.GetHardware(ctx, "Node")
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "get node data error", "err", err)
+		scrapeErr.set(err)
+		return
+	}
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(nodeID, nodeName string) {
+			defer wg.Done()
+			perfData, err := c.client.GetMetricNode(ctx, nodeID, c.interval)
+			if err != nil {
+				level.Warn(c.logger).Log("msg", "get node performance data error", "err", err)
+				scrapeErr.set(err)
+				return
+			}
+			if len(perfData) == 0 {
+				level.Warn(c.logger).Log("msg", "get node performance data is null")
+				return
+			}
+			for _, nodePerformance := range samplePoints(samples, nodeID, perfData) {
+				for _, metric := range metricNodeCollectorMetric {
+					metricDesc := c.metrics["node"+"_"+metric.name]
+					if metricValue := metric.value(nodePerformance.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, nodeName, nodePerformance.sample.ApplianceID), nodePerformance.pointTime)
+					}
+				}
+			}
+		}(node.ID, node.Name)
+	}
+	wg.Wait()
+	level.Info(c.logger).Log("msg", "Obtaining the performance node is successful", "time", time.Since(startTime))
+}
+
+func (c *metricNodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
+	for _, descMap := range c.metrics {
+		ch <- descMap
+	}
+}
+
+func getMetricNodeMetrics(ip string) map[string]*prometheus.Desc {
+	res := map[string]*prometheus.Desc{}
+	for _, metric := range metricNodeCollectorMetric {
+		res["node"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricNode_"+metric.name,
+			getMetricNodeDescByType(metric.name),
+			[]string{"node_id", "appliance_id"},
+			prometheus.Labels{"IP": ip})
+	}
+	return res
+}
+
+func getMetricNodeDescByType(key string) string {
+	if v, ok := metricNodeDescMap[key]; ok {
+		return v
+	} else {
+		return key
+	}
+}
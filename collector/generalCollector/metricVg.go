@@ -17,30 +17,21 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var metricVgCollectorMetric = []string{
-	"avg_read_latency",
-	"avg_latency",
-	"avg_write_latency",
-	"avg_read_iops",
-	"avg_read_bandwidth",
-	"avg_total_iops",
-	"avg_total_bandwidth",
-	"avg_write_iops",
-	"avg_write_bandwidth",
-	"avg_io_size",
-	"avg_read_size",
-	"avg_write_size",
-}
+var metricVgCollectorMetric = append(
+	ioPerformanceMetrics(func(s client.VgPerformance) client.IoPerformance { return s.IoPerformance }),
+	fieldMetric[client.VgPerformance]{"avg_io_size", func(s client.VgPerformance) *float64 { return s.AvgIoSize }},
+)
 
 var metricMetricVgDescMap = map[string]string{
 	"avg_read_latency":    "Average read latency in microseconds,unit is ms",
@@ -58,54 +49,69 @@ var metricMetricVgDescMap = map[string]string{
 }
 
 type metricVgCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricVgCollector(api *client.Client, logger log.Logger) *metricVgCollector {
-	metrics := getMetricVgfMetrics(api.IP)
+func NewMetricVgCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricVgCollector {
+	metrics := getMetricVgfMetrics(api.Address())
 	return &metricVgCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricVg", api.Address()),
+		samples:  newSampleMetrics("metricVg", api.Address(), config),
+		interval: intervalOf(config, "metricVg"),
+		logger:   logger,
 	}
 }
 
 func (c *metricVgCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricVgCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting volume group performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	vgArray := client.PowerstoreModuleID[c.client.IP]
-	for vgId, vgName := range vgArray["volumegroup"] {
+	for vgId, vg := range c.client.Inventory().Get(client.ModuleVolumeGroup) {
 		wg.Add(1)
 		go func(vgId, vgName string) {
 			defer wg.Done()
-			metricVgData, err := c.client.GetMetricVg(vgId)
+			metricVgData, err := c.client.GetMetricVg(ctx, vgId, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get volume group performance data error", "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			vgDataArray := gjson.Parse(metricVgData).Array()
-			if len(vgDataArray) == 0 {
+			if len(metricVgData) == 0 {
 				level.Warn(c.logger).Log("msg", "get volume group performance data is null")
 				return
 			}
-			vgData := vgDataArray[len(vgDataArray)-1]
-			for _, metricName := range metricVgCollectorMetric {
-				metricValue := vgData.Get(metricName)
-				metricDesc := c.metrics["vg"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), vgName)
+			for _, vgData := range samplePoints(samples, vgId, metricVgData) {
+				for _, metric := range metricVgCollectorMetric {
+					metricDesc := c.metrics["vg"+"_"+metric.name]
+					if metricValue := metric.value(vgData.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, vgName), vgData.pointTime)
+					}
 				}
 			}
-		}(vgId, vgName.String())
+		}(vgId, vg.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance volume group is successful", "time", time.Since(startTime))
 }
 
 func (c *metricVgCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -113,10 +119,10 @@ func (c *metricVgCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getMetricVgfMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range metricVgCollectorMetric {
-		res["vg"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_metricVg_"+metricName,
-			getMetricVgDescByType(metricName),
+	for _, metric := range metricVgCollectorMetric {
+		res["vg"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricVg_"+metric.name,
+			getMetricVgDescByType(metric.name),
 			[]string{"volume_group_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -17,30 +17,21 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var metricVolumeCollectorMetric = []string{
-	"avg_read_latency",
-	"avg_latency",
-	"avg_write_latency",
-	"avg_read_iops",
-	"avg_read_bandwidth",
-	"avg_total_iops",
-	"avg_total_bandwidth",
-	"avg_write_iops",
-	"avg_write_bandwidth",
-	"avg_io_size",
-	"avg_read_size",
-	"avg_write_size",
-}
+var metricVolumeCollectorMetric = append(
+	ioPerformanceMetrics(func(s client.VolumePerformance) client.IoPerformance { return s.IoPerformance }),
+	fieldMetric[client.VolumePerformance]{"avg_io_size", func(s client.VolumePerformance) *float64 { return s.AvgIoSize }},
+)
 
 var metricMetricVolumeDescMap = map[string]string{
 	"avg_read_latency":    "Average read latency in microseconds,unit is ms",
@@ -58,55 +49,69 @@ var metricMetricVolumeDescMap = map[string]string{
 }
 
 type metricVolumeCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewMetricVolumeCollector(api *client.Client, logger log.Logger) *metricVolumeCollector {
-	metrics := getMetricVolumeMetrics(api.IP)
+func NewMetricVolumeCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricVolumeCollector {
+	metrics := getMetricVolumeMetrics(api.Address())
 	return &metricVolumeCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("metricVolume", api.Address()),
+		samples:  newSampleMetrics("metricVolume", api.Address(), config),
+		interval: intervalOf(config, "metricVolume"),
+		logger:   logger,
 	}
 }
 
 func (c *metricVolumeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricVolumeCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting volume performance data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	volumeArray := client.PowerstoreModuleID[c.client.IP]
-	for volumeId, volumeName := range volumeArray["volume"] {
+	for volumeId, volume := range c.client.Inventory().Get(client.ModuleVolume) {
 		wg.Add(1)
 		go func(volumeId, volumeName string) {
 			defer wg.Done()
-			metricVolData, err := c.client.GetMetricVolume(volumeId)
+			metricVolData, err := c.client.GetMetricVolume(ctx, volumeId, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get volume performance data error", "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			volumeDataArray := gjson.Parse(metricVolData).Array()
-			if len(volumeDataArray) == 0 {
+			if len(metricVolData) == 0 {
 				level.Warn(c.logger).Log("msg", "get volume performance data is null")
 				return
 			}
-			volumeData := volumeDataArray[len(volumeDataArray)-1]
-			applianceID := volumeData.Get("appliance_id").String()
-			for _, metricName := range metricVolumeCollectorMetric {
-				metricValue := volumeData.Get(metricName)
-				metricDesc := c.metrics["volume"+"_"+metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), volumeName, applianceID)
+			for _, volumeData := range samplePoints(samples, volumeId, metricVolData) {
+				for _, metric := range metricVolumeCollectorMetric {
+					metricDesc := c.metrics["volume"+"_"+metric.name]
+					if metricValue := metric.value(volumeData.sample); metricValue != nil {
+						samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, volumeName, volumeData.sample.ApplianceID), volumeData.pointTime)
+					}
 				}
 			}
-		}(volumeId, volumeName.String())
+		}(volumeId, volume.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the performance volume is successful", "time", time.Since(startTime))
 }
 
 func (c *metricVolumeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -114,10 +119,10 @@ func (c *metricVolumeCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getMetricVolumeMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range metricVolumeCollectorMetric {
-		res["volume"+"_"+metricName] = prometheus.NewDesc(
-			"powerstore_metricVolume_"+metricName,
-			getMetricVolumeDescByType(metricName),
+	for _, metric := range metricVolumeCollectorMetric {
+		res["volume"+"_"+metric.name] = newSampleDesc(
+			"powerstore_metricVolume_"+metric.name,
+			getMetricVolumeDescByType(metric.name),
 			[]string{"volume_id", "appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
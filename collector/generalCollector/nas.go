@@ -17,13 +17,13 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
 var statuNasMetricsMap = map[string]map[string]int{
@@ -35,53 +35,57 @@ var metricNasDescMap = map[string]string{
 }
 
 type nasCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewNasCollector(api *client.Client, logger log.Logger) *nasCollector {
-	metrics := getNasMetrics(api.IP)
+func NewNasCollector(api client.PowerStoreAPI, logger log.Logger) *nasCollector {
+	metrics := getNasMetrics(api.Address())
 	return &nasCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("nas", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *nasCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *nasCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting nas server data")
 	startTime := time.Now()
-	nasData, err := c.client.GetNas()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	nasData, err := c.client.GetNas(ctx)
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "get Nas data error", "err", err)
+		scrapeErr.set(err)
 		return
 	}
-	for _, nas := range gjson.Parse(nasData).Array() {
-		name := nas.Get("name").String()
-		state := nas.Get("operational_status")
-		value := getNasFloatData("operational_status", state)
+	for _, nas := range nasData {
 		metricDesc := c.metrics["operational_status"]
-		if state.Exists() && state.Type != gjson.Null {
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, value, name)
+		if nas.OperationalStatus != nil {
+			value, err := getNasFloatData("operational_status", *nas.OperationalStatus)
+			if err != nil {
+				level.Error(c.logger).Log("msg", "parse nas server data error", "err", err, "nas", nas.Name)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, value, nas.Name)
 		}
 	}
 	level.Info(c.logger).Log("msg", "Obtaining the nas server is successful", "time", time.Since(startTime))
 }
 
-func getNasFloatData(key string, value gjson.Result) float64 {
-	if v, ok := statuNasMetricsMap[key]; ok {
-		if res, ok2 := v[value.String()]; ok2 {
-			return float64(res)
-		} else {
-			return float64(v["other"])
-		}
-	} else {
-		return value.Float()
-	}
+func getNasFloatData(key string, value string) (float64, error) {
+	return parseValue(statuNasMetricsMap, key, value)
 }
 
 func (c *nasCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -0,0 +1,62 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// otherState The key of the value of the states missing from a state map
+const otherState = "other"
+
+// stateValue Returns the value of the state in the state map, the value of other when the state is not listed
+func stateValue(states map[string]int, state string) float64 {
+	if value, ok := states[state]; ok {
+		return float64(value)
+	}
+	return float64(states[otherState])
+}
+
+// parseValue Returns the value of the key from its state map in metricMaps, or the value parsed as a number
+// when the key has no state map, an error when it is not a finite number
+func parseValue(metricMaps map[string]map[string]int, key, value string) (float64, error) {
+	if states, ok := metricMaps[key]; ok {
+		return stateValue(states, value), nil
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("%s %q is not a number", key, value)
+	}
+	return result, nil
+}
+
+// parseSpeed Returns the number of the port current_speed as is, e.g. 25 for 25_Gbps and 100 for 100_Mbps,
+// an error for a value like Auto
+func parseSpeed(value string) (float64, error) {
+	number, unit, found := strings.Cut(value, "_")
+	if !found || unit == "" {
+		return 0, fmt.Errorf("current_speed %q is not a speed", value)
+	}
+	speed, err := strconv.ParseUint(number, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("current_speed %q is not a speed", value)
+	}
+	return float64(speed), nil
+}
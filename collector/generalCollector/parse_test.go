@@ -0,0 +1,176 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"encoding/json"
+	"math"
+	"powerstore-metrics-exporter/collector/client"
+	"testing"
+)
+
+// stateMaps The state maps of the resource collectors by name
+var stateMaps = map[string]map[string]map[string]int{
+	"statuMetricsMap":        statuMetricsMap,
+	"metricHardwareValueMap": metricHardwareValueMap,
+	"statuNasMetricsMap":     statuNasMetricsMap,
+	"portStatusMetricMap":    portStatusMetricMap,
+	"statusVolumeMetricsMap": statusVolumeMetricsMap,
+}
+
+func TestStateMapsHaveOther(t *testing.T) {
+	for name, metricMaps := range stateMaps {
+		for key, states := range metricMaps {
+			if _, ok := states[otherState]; !ok {
+				t.Errorf("%s[%q] has no %q state", name, key, otherState)
+			}
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		maps    map[string]map[string]int
+		key     string
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"listed state", statuMetricsMap, "cluster_state", "Configured", 1, false},
+		{"other state", statuMetricsMap, "cluster_state", "Unconfigured", 0, false},
+		{"empty state", statuMetricsMap, "cluster_state", "", 0, false},
+		{"healthy hardware", metricHardwareValueMap, "lifecycle_state", "Healthy", 1, false},
+		{"failed hardware", metricHardwareValueMap, "lifecycle_state", "Failed", 0, false},
+		{"started nas", statuNasMetricsMap, "operational_status", "Started", 1, false},
+		{"stopped nas", statuNasMetricsMap, "operational_status", "Stopped", 0, false},
+		{"number", statuMetricsMap, "size", "1073741824", 1073741824, false},
+		{"float", statuMetricsMap, "size", "0.5", 0.5, false},
+		{"not a number", statuMetricsMap, "size", "Healthy", 0, true},
+		{"empty number", statuMetricsMap, "size", "", 0, true},
+		{"nan", statuMetricsMap, "size", "NaN", 0, true},
+		{"infinity", statuMetricsMap, "size", "+Inf", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseValue(test.maps, test.key, test.value)
+			if (err != nil) != test.wantErr || got != test.want {
+				t.Errorf("parseValue(%q, %q) = %v, %v, want %v, error %v", test.key, test.value, got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"25_Gbps", 25, false},
+		{"32_Gbps", 32, false},
+		{"1_Gbps", 1, false},
+		{"100_Mbps", 100, false},
+		{"10_Mbps", 10, false},
+		{"Auto", 0, true},
+		{"", 0, true},
+		{"_", 0, true},
+		{"_Gbps", 0, true},
+		{"25_", 0, true},
+		{"-1_Gbps", 0, true},
+		{"25_Tbps", 25, false},
+		{"1e3_Gbps", 0, true},
+		{"二十五_Gbps", 0, true},
+	}
+	for _, test := range tests {
+		got, err := parseSpeed(test.value)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("parseSpeed(%q) = %v, %v, want %v, error %v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestGetPortFloatDate(t *testing.T) {
+	up, auto := true, "Auto"
+	tests := []struct {
+		name    string
+		key     string
+		port    client.Port
+		want    float64
+		wantErr bool
+	}{
+		{"link up", "is_link_up", client.Port{IsLinkUp: &up}, 1, false},
+		{"no link state", "is_link_up", client.Port{}, 0, false},
+		{"no speed", "current_speed", client.Port{}, 0, false},
+		{"auto speed", "current_speed", client.Port{CurrentSpeed: &auto}, 0, true},
+		{"unknown metric", "mtu", client.Port{}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := getPortFloatDate(test.key, test.port)
+			if (err != nil) != test.wantErr || got != test.want {
+				t.Errorf("getPortFloatDate(%q) = %v, %v, want %v, error %v", test.key, got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func FuzzParseSpeed(f *testing.F) {
+	for _, seed := range []string{"25_Gbps", "100_Mbps", "Auto", "", "_", "4294967296_Gbps", "1_Gbps_"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		speed, err := parseSpeed(value)
+		if err == nil && (speed < 0 || math.IsInf(speed, 0) || math.IsNaN(speed)) {
+			t.Errorf("parseSpeed(%q) = %v", value, speed)
+		}
+	})
+}
+
+func FuzzParseValue(f *testing.F) {
+	for _, seed := range []string{"Configured", "other", "", "1.5", "-0", "NaN", "1e400", "0x1p-2"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, key := range []string{"cluster_state", "size"} {
+			result, err := parseValue(statuMetricsMap, key, value)
+			if err == nil && (math.IsInf(result, 0) || math.IsNaN(result)) {
+				t.Errorf("parseValue(%q, %q) = %v", key, value, result)
+			}
+		}
+	})
+}
+
+// FuzzPortPayload Decode a port collection and parse its metrics like the port collector
+func FuzzPortPayload(f *testing.F) {
+	f.Add(`[{"id":"p1","name":"port","appliance_id":"A1","is_link_up":true,"current_speed":"25_Gbps"}]`)
+	f.Add(`[{"current_speed":"Auto"},{"current_speed":null,"is_link_up":null}]`)
+	f.Add(`[{"current_speed":""}]`)
+	f.Fuzz(func(t *testing.T, payload string) {
+		var ports []client.Port
+		if err := json.Unmarshal([]byte(payload), &ports); err != nil {
+			return
+		}
+		for _, port := range ports {
+			for _, metricName := range portCollectorMetrics {
+				if value, err := getPortFloatDate(metricName, port); err == nil && math.IsNaN(value) {
+					t.Errorf("getPortFloatDate(%q) of %+v is NaN", metricName, port)
+				}
+			}
+		}
+	})
+}
@@ -17,15 +17,14 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
 var portTypes = []string{
@@ -39,7 +38,7 @@ var portCollectorMetrics = []string{
 }
 
 var portStatusMetricMap = map[string]map[string]int{
-	"is_link_up": {"true": 1, "false": 0},
+	"is_link_up": {"true": 1, "false": 0, "other": 0},
 }
 
 // port description
@@ -49,36 +48,47 @@ var metricPortDescMap = map[string]string{
 }
 
 type portCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewPortCollector(api *client.Client, logger log.Logger) *portCollector {
-	metrics := getPortMetrics(api.IP)
+func NewPortCollector(api client.PowerStoreAPI, logger log.Logger) *portCollector {
+	metrics := getPortMetrics(api.Address())
 	return &portCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("port", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *portCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *portCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting port data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
 	for _, portType := range portTypes {
-		portTypeData, err := c.client.GetPort(portType)
+		portTypeData, err := c.client.GetPort(ctx, portType)
 		if err != nil {
 			level.Warn(c.logger).Log("msg", "get "+portType+" data error", "err", err)
+			scrapeErr.set(err)
 			return
 		}
-		for _, data := range gjson.Parse(portTypeData).Array() {
-			name := data.Get("name").String()
-			id := data.Get("appliance_id").String()
+		for _, port := range portTypeData {
 			for _, metricName := range portCollectorMetrics {
-				metricValue := getPortFloatDate(metricName, data.Get(metricName))
+				metricValue, err := getPortFloatDate(metricName, port)
+				if err != nil {
+					level.Error(c.logger).Log("msg", "parse "+portType+" data error", "err", err, "port", port.Name)
+					continue
+				}
 				metricDesc := c.metrics[portType+metricName]
-				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue, id, name)
+				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue, port.ApplianceID, port.Name)
 			}
 		}
 	}
@@ -86,28 +96,28 @@ func (c *portCollector) Collect(ch chan<- prometheus.Metric) {
 }
 
 func (c *portCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
 }
 
-func getPortFloatDate(key string, value gjson.Result) float64 {
-	if v, ok := portStatusMetricMap[key]; ok {
-		if res, ok2 := v[value.String()]; ok2 {
-			return float64(res)
-		} else {
-			return float64(v["other"])
+func getPortFloatDate(key string, port client.Port) (float64, error) {
+	switch key {
+	case "is_link_up":
+		var value string
+		if port.IsLinkUp != nil {
+			value = strconv.FormatBool(*port.IsLinkUp)
 		}
-	} else if key == "current_speed" {
-		if value.Type == gjson.Null {
-			return 0
+		return stateValue(portStatusMetricMap[key], value), nil
+	case "current_speed":
+		// the speed of a port without link may be null, it is reported as 0
+		if port.CurrentSpeed == nil {
+			return 0, nil
 		}
-		rs := []rune(value.String())
-		speed := string(rs[0:strings.Index(value.String(), "_")])
-		result, _ := strconv.Atoi(speed)
-		return float64(result)
-	} else {
-		return value.Float()
+		return parseSpeed(*port.CurrentSpeed)
+	default:
+		return 0, nil
 	}
 }
 
@@ -0,0 +1,283 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"context"
+	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sampleDescs The name and help of the descs created by newSampleDesc, which prometheus.Desc does not expose
+var sampleDescs sync.Map
+
+type sampleDesc struct {
+	name string
+	help string
+}
+
+// newSampleDesc Create the desc of a metric emitted by sampleBatch.emit
+func newSampleDesc(fqName, help string, variableLabels []string, constLabels prometheus.Labels) *prometheus.Desc {
+	desc := prometheus.NewDesc(fqName, help, variableLabels, constLabels)
+	sampleDescs.Store(desc, sampleDesc{name: fqName, help: help})
+	return desc
+}
+
+// sampleMetrics Emits the samples returned by metrics/generate, stamped with their powerstore timestamp when configured.
+// In gap free mode every sample newer than the last one exposed for the entity is emitted, the older ones through
+// the backfill of the exposition, and the cursors of the entities move once the exposition is written.
+type sampleMetrics struct {
+	timestamps bool
+	gapFree    bool
+	age        *prometheus.Desc
+	cursors    *sampleCursors
+}
+
+func newSampleMetrics(collector, ip string, config utils.Metrics) sampleMetrics {
+	return sampleMetrics{
+		timestamps: config.SampleTimestamps || config.GapFree,
+		gapFree:    config.GapFree,
+		age: prometheus.NewDesc(
+			"powerstore_sample_age_seconds",
+			"Seconds between the collection and the newest sample returned by the powerstore,unit is s",
+			nil, prometheus.Labels{"IP": ip, "collector": collector}),
+		cursors: &sampleCursors{last: make(map[string]time.Time)},
+	}
+}
+
+func (s sampleMetrics) describe(ch chan<- *prometheus.Desc) {
+	ch <- s.age
+}
+
+// newBatch Starts the samples of one collection, exposed through the exposition of ctx in gap free mode
+func (s sampleMetrics) newBatch(ctx context.Context, ch chan<- prometheus.Metric) *sampleBatch {
+	return &sampleBatch{
+		sampleMetrics: s,
+		ch:            ch,
+		exposition:    utils.ExpositionFrom(ctx),
+		backfill:      &sampleBackfill{families: make(map[string]*dto.MetricFamily)},
+		pending:       make(map[string]time.Time),
+	}
+}
+
+// samplePoint One sample of an entity with its emission time
+type samplePoint[S client.Sample] struct {
+	sample S
+	pointTime
+}
+
+// pointTime The powerstore timestamp of a sample, backfill is set for the samples older than the newest one
+type pointTime struct {
+	ts       time.Time
+	backfill bool
+}
+
+// sampleBatch The samples of one collection, safe for use by the goroutines of a collector
+type sampleBatch struct {
+	sampleMetrics
+	ch         chan<- prometheus.Metric
+	exposition *utils.Exposition
+	backfill   *sampleBackfill
+	lock       sync.Mutex
+	newest     time.Time
+	// pending The newest sample emitted for each entity, the cursors move there once the exposition is written
+	pending map[string]time.Time
+}
+
+// samplePoints Returns the samples of the entity to emit, oldest first. That is the newest sample,
+// and in gap free mode also the ones newer than the last sample exposed for the entity.
+// The history returned by the first collection of an entity is not emitted, nor is the history of a collection
+// without exposition.
+func samplePoints[S client.Sample](b *sampleBatch, entity string, samples []S) []samplePoint[S] {
+	if len(samples) == 0 {
+		return nil
+	}
+	newest := samples[len(samples)-1]
+	latest := samplePoint[S]{sample: newest, pointTime: pointTime{ts: newest.SampleTime()}}
+	if latest.ts.IsZero() {
+		return []samplePoint[S]{latest}
+	}
+	b.lock.Lock()
+	if latest.ts.After(b.newest) {
+		b.newest = latest.ts
+	}
+	if b.gapFree && b.exposition != nil && latest.ts.After(b.pending[entity]) {
+		b.pending[entity] = latest.ts
+	}
+	b.lock.Unlock()
+	if !b.gapFree || b.exposition == nil {
+		return []samplePoint[S]{latest}
+	}
+	last, ok := b.cursors.get(entity)
+	var points []samplePoint[S]
+	if ok {
+		for _, sample := range samples[:len(samples)-1] {
+			ts := sample.SampleTime()
+			if ts.After(last) && ts.Before(latest.ts) {
+				points = append(points, samplePoint[S]{sample: sample, pointTime: pointTime{ts: ts, backfill: true}})
+			}
+		}
+	}
+	return append(points, latest)
+}
+
+// fieldMetric A metric and the field of the resource or sample S it is read from, nil when the powerstore returned no value
+type fieldMetric[S any] struct {
+	name  string
+	value func(S) *float64
+}
+
+// ioPerformanceMetrics The latency, iops, bandwidth and size metrics shared by the performance entities
+func ioPerformanceMetrics[S any](io func(S) client.IoPerformance) []fieldMetric[S] {
+	return []fieldMetric[S]{
+		{"avg_read_latency", func(s S) *float64 { return io(s).AvgReadLatency }},
+		{"avg_latency", func(s S) *float64 { return io(s).AvgLatency }},
+		{"avg_write_latency", func(s S) *float64 { return io(s).AvgWriteLatency }},
+		{"avg_read_iops", func(s S) *float64 { return io(s).AvgReadIops }},
+		{"avg_read_bandwidth", func(s S) *float64 { return io(s).AvgReadBandwidth }},
+		{"avg_total_iops", func(s S) *float64 { return io(s).AvgTotalIops }},
+		{"avg_total_bandwidth", func(s S) *float64 { return io(s).AvgTotalBandwidth }},
+		{"avg_write_iops", func(s S) *float64 { return io(s).AvgWriteIops }},
+		{"avg_write_bandwidth", func(s S) *float64 { return io(s).AvgWriteBandwidth }},
+		{"avg_read_size", func(s S) *float64 { return io(s).AvgReadSize }},
+		{"avg_write_size", func(s S) *float64 { return io(s).AvgWriteSize }},
+	}
+}
+
+// emit Send the metric of a sample, with the sample timestamp when configured
+func (b *sampleBatch) emit(metric prometheus.Metric, point pointTime) {
+	if point.backfill {
+		b.backfill.push(metric, point.ts)
+		return
+	}
+	if b.timestamps && !point.ts.IsZero() {
+		metric = prometheus.NewMetricWithTimestamp(point.ts, metric)
+	}
+	b.ch <- metric
+}
+
+// done Send the age of the newest sample of the collection, and hand the backfill and the cursors to the exposition
+func (b *sampleBatch) done() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.newest.IsZero() {
+		b.ch <- prometheus.MustNewConstMetric(b.age, prometheus.GaugeValue, time.Since(b.newest).Seconds())
+	}
+	if b.exposition != nil && len(b.pending) > 0 {
+		b.exposition.Backfill(b.backfill.drain())
+		cursors, pending, newest := b.cursors, b.pending, b.newest
+		b.exposition.OnCommit(func() {
+			cursors.commit(pending)
+			cursors.prune(newest.Add(-cursorRetention))
+		})
+	}
+}
+
+// cursorRetention How long the last emitted timestamp of an entity not seen anymore is kept
+const cursorRetention = 24 * time.Hour
+
+// sampleCursors The timestamp of the last sample exposed for each entity
+type sampleCursors struct {
+	lock sync.Mutex
+	last map[string]time.Time
+}
+
+// get Returns the timestamp of the last sample exposed for the entity
+func (c *sampleCursors) get(entity string) (time.Time, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	last, ok := c.last[entity]
+	return last, ok
+}
+
+// commit Move the cursors forward to the timestamps of the samples exposed, the exposition of an overlapping scrape
+// may have moved them further already
+func (c *sampleCursors) commit(exposed map[string]time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for entity, ts := range exposed {
+		if last, ok := c.last[entity]; !ok || ts.After(last) {
+			c.last[entity] = ts
+		}
+	}
+}
+
+// prune Forget the entities whose last sample is older than before, e.g. deleted volumes
+func (c *sampleCursors) prune(before time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for entity, last := range c.last {
+		if last.Before(before) {
+			delete(c.last, entity)
+		}
+	}
+}
+
+// sampleBackfill The older samples of a collection, a registry only accepts one sample per series
+type sampleBackfill struct {
+	lock     sync.Mutex
+	families map[string]*dto.MetricFamily
+}
+
+func (f *sampleBackfill) push(metric prometheus.Metric, ts time.Time) {
+	value, ok := sampleDescs.Load(metric.Desc())
+	if !ok {
+		return
+	}
+	desc := value.(sampleDesc)
+	pb := &dto.Metric{}
+	if err := metric.Write(pb); err != nil {
+		return
+	}
+	timestampMs := ts.UnixNano() / int64(time.Millisecond)
+	pb.TimestampMs = &timestampMs
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	family, ok := f.families[desc.name]
+	if !ok {
+		family = &dto.MetricFamily{
+			Name: &desc.name,
+			Help: &desc.help,
+			Type: dto.MetricType_GAUGE.Enum(),
+		}
+		f.families[desc.name] = family
+	}
+	family.Metric = append(family.Metric, pb)
+}
+
+// drain Returns the samples pushed since the last drain, oldest first
+func (f *sampleBackfill) drain() []*dto.MetricFamily {
+	f.lock.Lock()
+	families := f.families
+	f.families = make(map[string]*dto.MetricFamily)
+	f.lock.Unlock()
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		metrics := family.Metric
+		sort.SliceStable(metrics, func(i, j int) bool {
+			return metrics[i].GetTimestampMs() < metrics[j].GetTimestampMs()
+		})
+		result = append(result, family)
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"context"
+	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var testSampleDesc = newSampleDesc("powerstore_test_sample", "test sample", nil, nil)
+
+// collectSamples Collect the samples of one entity like a performance collector, and return the number of samples
+// emitted and backfilled and the commit of the exposition, nil when collected without exposition
+func collectSamples(t *testing.T, metrics sampleMetrics, exposed bool, times ...time.Time) (int, int, func()) {
+	t.Helper()
+	ctx := context.Background()
+	var exposition *utils.Exposition
+	if exposed {
+		exposition = utils.NewExposition()
+		ctx = utils.WithExposition(ctx, exposition)
+	}
+	samples := make([]client.MetricSample, 0, len(times))
+	for _, ts := range times {
+		samples = append(samples, client.MetricSample{Timestamp: ts})
+	}
+	ch := make(chan prometheus.Metric, 2*len(times)+1)
+	batch := metrics.newBatch(ctx, ch)
+	for _, point := range samplePoints(batch, "volume-1", samples) {
+		batch.emit(prometheus.MustNewConstMetric(testSampleDesc, prometheus.GaugeValue, 1), point.pointTime)
+	}
+	batch.done()
+	close(ch)
+	emitted := 0
+	for metric := range ch {
+		if metric.Desc() == testSampleDesc {
+			emitted++
+		}
+	}
+	if exposition == nil {
+		return emitted, 0, nil
+	}
+	families, err := exposition.Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return nil, nil
+	})).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backfilled := 0
+	for _, family := range families {
+		backfilled += len(family.Metric)
+	}
+	return emitted, backfilled, exposition.Commit
+}
+
+func TestGapFreeCommit(t *testing.T) {
+	metrics := newSampleMetrics("test", "192.0.2.1", utils.Metrics{GapFree: true})
+	t0 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	step := 5 * time.Minute
+
+	emitted, backfilled, commit := collectSamples(t, metrics, true, t0.Add(-step), t0)
+	if emitted != 1 || backfilled != 0 {
+		t.Fatalf("first collection emitted %d and backfilled %d samples, want the newest only", emitted, backfilled)
+	}
+	commit()
+
+	// the response of this collection is never written
+	emitted, backfilled, _ = collectSamples(t, metrics, true, t0, t0.Add(step), t0.Add(2*step))
+	if emitted != 1 || backfilled != 1 {
+		t.Fatalf("collection emitted %d and backfilled %d samples, want 1 and 1", emitted, backfilled)
+	}
+
+	emitted, backfilled, commit = collectSamples(t, metrics, true, t0, t0.Add(step), t0.Add(2*step))
+	if emitted != 1 || backfilled != 1 {
+		t.Fatalf("the samples of the abandoned collection were lost: emitted %d and backfilled %d, want 1 and 1", emitted, backfilled)
+	}
+	commit()
+
+	emitted, backfilled, commit = collectSamples(t, metrics, true, t0.Add(step), t0.Add(2*step), t0.Add(3*step))
+	if emitted != 1 || backfilled != 0 {
+		t.Fatalf("collection after the commit emitted %d and backfilled %d samples, want the newest only", emitted, backfilled)
+	}
+	commit()
+}
+
+func TestGapFreeWithoutExposition(t *testing.T) {
+	metrics := newSampleMetrics("test", "192.0.2.1", utils.Metrics{GapFree: true})
+	t0 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if emitted, _, _ := collectSamples(t, metrics, false, t0, t0.Add(5*time.Minute), t0.Add(10*time.Minute)); emitted != 1 {
+			t.Fatalf("collection %d emitted %d samples, want the newest only", i, emitted)
+		}
+	}
+	if _, ok := metrics.cursors.get("volume-1"); ok {
+		t.Error("a collection without exposition moved the cursor")
+	}
+}
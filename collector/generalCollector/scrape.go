@@ -0,0 +1,94 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package generalCollector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeMetrics The success and duration of one collector, so that missing data can be alerted on
+type scrapeMetrics struct {
+	success  *prometheus.Desc
+	duration *prometheus.Desc
+	partial  prometheus.Counter
+}
+
+var scrapePartial = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "powerstore_scrape_partial_total",
+	Help: "Number of collections cancelled before getting all their data, e.g. because the prometheus scrape timed out",
+}, []string{"IP", "collector"})
+
+func init() {
+	prometheus.MustRegister(scrapePartial)
+}
+
+func newScrapeMetrics(collector, ip string) scrapeMetrics {
+	labels := prometheus.Labels{"IP": ip, "collector": collector}
+	return scrapeMetrics{
+		partial: scrapePartial.WithLabelValues(ip, collector),
+		success: prometheus.NewDesc(
+			"powerstore_scrape_collector_success",
+			"Whether the collector got all its data from the powerstore,1 is success,0 is failure",
+			nil, labels),
+		duration: prometheus.NewDesc(
+			"powerstore_scrape_collector_duration_seconds",
+			"Duration of the collector,unit is s",
+			nil, labels),
+	}
+}
+
+func (s scrapeMetrics) describe(ch chan<- *prometheus.Desc) {
+	ch <- s.success
+	ch <- s.duration
+}
+
+func (s scrapeMetrics) collect(ch chan<- prometheus.Metric, startTime time.Time, err error) {
+	success := 1.0
+	if err != nil {
+		success = 0
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		s.partial.Inc()
+	}
+	ch <- prometheus.MustNewConstMetric(s.success, prometheus.GaugeValue, success)
+	ch <- prometheus.MustNewConstMetric(s.duration, prometheus.GaugeValue, time.Since(startTime).Seconds())
+}
+
+// collectError Keeps the first error of a collection, safe for use by the goroutines of a collector
+type collectError struct {
+	lock sync.Mutex
+	err  error
+}
+
+func (e *collectError) set(err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *collectError) get() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.err
+}
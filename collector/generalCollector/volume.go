@@ -17,13 +17,13 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
 var volumeCollectorMetrics = []string{
@@ -43,56 +43,73 @@ var statusVolumeMetricsMap = map[string]map[string]int{
 }
 
 type volumeCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewVolumeCollector(api *client.Client, logger log.Logger) *volumeCollector {
-	metrics := getVolumeMetrics(api.IP)
+func NewVolumeCollector(api client.PowerStoreAPI, logger log.Logger) *volumeCollector {
+	metrics := getVolumeMetrics(api.Address())
 	return &volumeCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("volume", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *volumeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *volumeCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting volume data")
 	startTime := time.Now()
-	volumeData, err := c.client.GetVolume()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	volumeData, err := c.client.GetVolume(ctx)
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "get volume data error", "err", err)
+		scrapeErr.set(err)
 		return
 	}
-	for _, volume := range gjson.Parse(volumeData).Array() {
-		name := volume.Get("name").String()
-		id := volume.Get("appliance_id").String()
+	for _, volume := range volumeData {
 		for _, metricName := range volumeCollectorMetrics {
-			metricValue := getVolumeFloatDate(metricName, volume.Get(metricName))
+			metricValue := getVolumeFloatDate(metricName, volume)
 			metricDesc := c.metrics[metricName]
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue, name, id)
+			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue, volume.Name, volume.ApplianceID)
 		}
 	}
 	level.Info(c.logger).Log("msg", "Obtaining the volume is successful", "time", time.Since(startTime))
 }
 
 func (c *volumeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
 }
 
-func getVolumeFloatDate(key string, value gjson.Result) float64 {
-	if v, ok := statusVolumeMetricsMap[key]; ok {
-		if res, ok2 := v[value.String()]; ok2 {
-			return float64(res)
-		} else {
-			return float64(v["other"])
-		}
-	} else {
-		return value.Float()
+func getVolumeFloatDate(key string, volume client.Volume) float64 {
+	switch key {
+	case "state":
+		return stateValue(statusVolumeMetricsMap[key], volume.State)
+	case "size":
+		return floatOrZero(volume.Size)
+	case "logical_used":
+		return floatOrZero(volume.LogicalUsed)
+	default:
+		return 0
+	}
+}
+
+// floatOrZero Returns the value, 0 when the powerstore returned none
+func floatOrZero(value *float64) float64 {
+	if value == nil {
+		return 0
 	}
+	return *value
 }
 
 func getVolumeMetrics(ip string) map[string]*prometheus.Desc {
@@ -17,18 +17,18 @@
 package generalCollector
 
 import (
+	"context"
 	"powerstore-metrics-exporter/collector/client"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 )
 
-var volumeGroupCollectorMetrics = []string{
-	"logical_provisioned",
-	"logical_used",
+var volumeGroupCollectorMetrics = []fieldMetric[client.VolumeGroup]{
+	{"logical_provisioned", func(vg client.VolumeGroup) *float64 { return vg.LogicalProvisioned }},
+	{"logical_used", func(vg client.VolumeGroup) *float64 { return vg.LogicalUsed }},
 }
 
 var metricVolumeGroupDescMap = map[string]string{
@@ -37,36 +37,43 @@ var metricVolumeGroupDescMap = map[string]string{
 }
 
 type volumeGroupCollector struct {
-	client  *client.Client
+	client  client.PowerStoreAPI
 	metrics map[string]*prometheus.Desc
+	scrape  scrapeMetrics
 	logger  log.Logger
 }
 
-func NewVolumeGroupCollector(api *client.Client, logger log.Logger) *volumeGroupCollector {
-	metrics := getVolumeGroupMetrics(api.IP)
+func NewVolumeGroupCollector(api client.PowerStoreAPI, logger log.Logger) *volumeGroupCollector {
+	metrics := getVolumeGroupMetrics(api.Address())
 	return &volumeGroupCollector{
 		client:  api,
 		metrics: metrics,
+		scrape:  newScrapeMetrics("volumeGroup", api.Address()),
 		logger:  logger,
 	}
 }
 
 func (c *volumeGroupCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *volumeGroupCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting volume group data")
 	startTime := time.Now()
-	volumeGroupData, err := c.client.GetVolumeGroup()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	volumeGroupData, err := c.client.GetVolumeGroup(ctx)
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "get volume group data error", "err", err)
+		scrapeErr.set(err)
 		return
 	}
-	for _, volumeGroup := range gjson.Parse(volumeGroupData).Array() {
-		name := volumeGroup.Get("name").String()
-		for _, applianceID := range volumeGroup.Get("appliance_ids").Array() {
-			for _, metricName := range volumeGroupCollectorMetrics {
-				metricValue := volumeGroup.Get(metricName)
-				metricDesc := c.metrics[metricName]
-				if metricValue.Exists() && metricValue.Type != gjson.Null {
-					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricValue.Float(), name, applianceID.String())
+	for _, volumeGroup := range volumeGroupData {
+		for _, applianceID := range volumeGroup.ApplianceIDs {
+			for _, metric := range volumeGroupCollectorMetrics {
+				metricDesc := c.metrics[metric.name]
+				if metricValue := metric.value(volumeGroup); metricValue != nil {
+					ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricValue, volumeGroup.Name, applianceID)
 				}
 			}
 		}
@@ -75,6 +82,7 @@ func (c *volumeGroupCollector) Collect(ch chan<- prometheus.Metric) {
 }
 
 func (c *volumeGroupCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -82,10 +90,10 @@ func (c *volumeGroupCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getVolumeGroupMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	for _, metricName := range volumeGroupCollectorMetrics {
-		res[metricName] = prometheus.NewDesc(
-			"powerstore_volumegroup_"+metricName,
-			getVolumeGroupDescByType(metricName),
+	for _, metric := range volumeGroupCollectorMetrics {
+		res[metric.name] = prometheus.NewDesc(
+			"powerstore_volumegroup_"+metric.name,
+			getVolumeGroupDescByType(metric.name),
 			[]string{"name", "appliance_id"},
 			prometheus.Labels{"IP": ip})
 	}
@@ -17,63 +17,78 @@
 package generalCollector
 
 import (
+	"context"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tidwall/gjson"
 	"powerstore-metrics-exporter/collector/client"
+	"powerstore-metrics-exporter/utils"
 	"sync"
 	"time"
 )
 
 type metricWearMetricCollector struct {
-	client  *client.Client
-	metrics map[string]*prometheus.Desc
-	logger  log.Logger
+	client   client.PowerStoreAPI
+	metrics  map[string]*prometheus.Desc
+	scrape   scrapeMetrics
+	samples  sampleMetrics
+	interval string
+	logger   log.Logger
 }
 
-func NewWearMetricCollector(api *client.Client, logger log.Logger) *metricWearMetricCollector {
-	metrics := getWearMetrics(api.IP)
+func NewWearMetricCollector(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) *metricWearMetricCollector {
+	metrics := getWearMetrics(api.Address())
 	return &metricWearMetricCollector{
-		client:  api,
-		metrics: metrics,
-		logger:  logger,
+		client:   api,
+		metrics:  metrics,
+		scrape:   newScrapeMetrics("wear", api.Address()),
+		samples:  newSampleMetrics("wear", api.Address(), config),
+		interval: intervalOf(config, "wear"),
+		logger:   logger,
 	}
 }
 
 func (c *metricWearMetricCollector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+func (c *metricWearMetricCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	level.Info(c.logger).Log("msg", "Start collecting driver percent endurance remaining data")
 	startTime := time.Now()
+	var scrapeErr collectError
+	defer func() { c.scrape.collect(ch, startTime, scrapeErr.get()) }()
+	samples := c.samples.newBatch(ctx, ch)
+	defer samples.done()
 	var wg sync.WaitGroup
-	driveArray := client.PowerstoreModuleID[c.client.IP]
-	for driveID, driveName := range driveArray["drive"] {
+	for driveID, drive := range c.client.Inventory().Get(client.ModuleDrive) {
 		wg.Add(1)
 		go func(driveID, driveName string) {
 			defer wg.Done()
-			result, err := c.client.GetWearMetricByDrive(driveID)
+			result, err := c.client.GetWearMetricByDrive(ctx, driveID, c.interval)
 			if err != nil {
 				level.Warn(c.logger).Log("msg", "get driver percent endurance remaining data error", "driver_id", driveID, "err", err)
+				scrapeErr.set(err)
 				return
 			}
-			metricWearArray := gjson.Parse(result).Array()
-			if len(metricWearArray) == 0 {
+			if len(result) == 0 {
 				level.Warn(c.logger).Log("msg", "get driver percent endurance remaining data empty", "driver_id")
 				return
 			}
-			wearData := metricWearArray[len(metricWearArray)-1]
-			applianceID := wearData.Get("appliance_id").String()
-			metricsValue := wearData.Get("percent_endurance_remaining")
-			metricDesc := c.metrics["wear"]
-			if metricsValue.Exists() && metricsValue.Type != gjson.Null {
-				ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, metricsValue.Float(), driveName, applianceID)
+			for _, wearData := range samplePoints(samples, driveID, result) {
+				metricDesc := c.metrics["wear"]
+				if metricsValue := wearData.sample.PercentEnduranceRemaining; metricsValue != nil {
+					samples.emit(prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, *metricsValue, driveName, wearData.sample.ApplianceID), wearData.pointTime)
+				}
 			}
-		}(driveID, driveName.String())
+		}(driveID, drive.Name)
 	}
 	wg.Wait()
 	level.Info(c.logger).Log("msg", "Obtaining the driver percent endurance remaining is successful", "time", time.Since(startTime))
 }
 
 func (c *metricWearMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.scrape.describe(ch)
+	c.samples.describe(ch)
 	for _, descMap := range c.metrics {
 		ch <- descMap
 	}
@@ -81,7 +96,7 @@ func (c *metricWearMetricCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func getWearMetrics(ip string) map[string]*prometheus.Desc {
 	res := map[string]*prometheus.Desc{}
-	res["wear"] = prometheus.NewDesc(
+	res["wear"] = newSampleDesc(
 		"powerstore_wear_metrics_by_drive",
 		"The percentage of drive wear remaining.",
 		[]string{"name", "appliance_id"},
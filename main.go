@@ -18,6 +18,7 @@ package main
 
 import (
 	"flag"
+	stdlog "log"
 	"powerstore-metrics-exporter/route"
 	"powerstore-metrics-exporter/utils"
 
@@ -28,14 +29,21 @@ var (
 	loggers    log.Logger
 	config     *utils.Config
 	configPath string
+	traffic    utils.Traffic
 )
 
 func init() {
 	flag.StringVar(&configPath, "c", "config.yml", "powerstore exporter configuration file path")
+	flag.StringVar(&traffic.Record, "record", "", "directory the sanitized powerstore requests and responses are recorded to")
+	flag.StringVar(&traffic.Replay, "replay", "", "directory of recorded powerstore requests and responses answering the requests instead of the powerstores")
 	flag.Parse()
+	if traffic.Record != "" && traffic.Replay != "" {
+		stdlog.Fatalln("-record and -replay cannot be used together")
+	}
 	config = utils.GetConfig(configPath)
+	config.Traffic = traffic
 	loggers = utils.GetLogger(config.Log.Level, config.Log.Path, config.Log.Type)
-	utils.InitReqCounter(config.Exporter.ReqLimit)
+	utils.InitScheduler(config.Exporter.ReqLimit)
 }
 
 func main() {
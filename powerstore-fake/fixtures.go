@@ -0,0 +1,378 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package powerstorefake
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// Topology The number of objects generated for the fake powerstore, the per appliance counts apply to every appliance
+type Topology struct {
+	Appliances int
+	// Volumes, VolumeGroups, EthPorts, FcPorts and Drives are per appliance
+	Volumes      int
+	VolumeGroups int
+	EthPorts     int
+	FcPorts      int
+	Drives       int
+	NasServers   int
+	// FileSystems are per nas server
+	FileSystems int
+	// Samples The number of samples returned by metrics/generate for an entity
+	Samples int
+}
+
+// DefaultTopology A small powerstore with a few objects of each type
+func DefaultTopology() Topology {
+	return Topology{
+		Appliances:   1,
+		Volumes:      3,
+		VolumeGroups: 1,
+		EthPorts:     2,
+		FcPorts:      2,
+		Drives:       2,
+		NasServers:   1,
+		FileSystems:  2,
+		Samples:      3,
+	}
+}
+
+// Fixtures The objects served by the fake powerstore, keyed by rest api resource. The objects are written with the
+// field names of the powerstore rest api reference, not derived from the models of the exporter, so that a model
+// decoding a field under another name misses it like it would on an array.
+type Fixtures struct {
+	Collections map[string][]map[string]interface{}
+	Samples     int
+}
+
+// The float fields of the metrics/generate samples, by entity
+var (
+	ioPerformanceFields = []string{
+		"avg_read_latency", "avg_latency", "avg_write_latency",
+		"avg_read_iops", "avg_read_bandwidth", "avg_total_iops", "avg_total_bandwidth",
+		"avg_write_iops", "avg_write_bandwidth", "avg_read_size", "avg_write_size",
+	}
+	fcPortPerformanceFields = []string{
+		"avg_read_latency", "avg_latency", "avg_write_latency", "avg_total_iops", "avg_total_bandwidth",
+		"avg_dumped_frames_ps", "avg_loss_of_signal_count_ps", "avg_invalid_crc_count_ps", "avg_loss_of_sync_count_ps",
+		"avg_invalid_tx_word_count_ps", "avg_prim_seq_prot_err_count_ps", "avg_link_failure_count_ps",
+	}
+	ethPortPerformanceFields = []string{
+		"avg_bytes_rx_ps", "avg_bytes_tx_ps", "avg_pkt_rx_crc_error_ps", "avg_pkt_rx_no_buffer_error_ps",
+		"avg_pkt_rx_ps", "avg_pkt_tx_error_ps", "avg_pkt_tx_ps",
+	}
+	applianceSpaceFields = []string{
+		"last_logical_provisioned", "last_logical_used", "last_physical_total", "last_physical_used",
+		"max_logical_provisioned", "max_logical_used", "max_physical_total", "max_physical_used",
+		"last_data_physical_used", "max_data_physical_used", "last_efficiency_ratio", "last_data_reduction",
+		"last_snapshot_savings", "last_thin_savings", "max_efficiency_ratio", "max_data_reduction",
+		"max_snapshot_savings", "max_thin_savings", "last_shared_logical_used", "max_shared_logical_used",
+	}
+	filesystemPerformanceFields = []string{
+		"avg_size", "avg_block_write_iops", "avg_mirror_write_iops", "avg_block_write_bandwidth",
+		"avg_mirror_write_bandwidth", "avg_block_write_latency", "avg_mirror_overhead_latency",
+	}
+)
+
+// allIntervals and longIntervals The intervals of metrics/generate, the entities without Twenty_Sec support the long ones only
+var (
+	allIntervals  = []string{"Twenty_Sec", "Five_Mins", "One_Hour", "One_Day"}
+	longIntervals = []string{"Five_Mins", "One_Hour", "One_Day"}
+)
+
+// sampleEntities The metrics/generate entities, the resource their entity_id belongs to, the field of the id in
+// their samples, their float fields and the intervals they support
+var sampleEntities = map[string]struct {
+	resource  string
+	idField   string
+	fields    []string
+	intervals []string
+}{
+	"performance_metrics_by_appliance": {"appliance", "appliance_id",
+		concat(ioPerformanceFields, "avg_io_size", "avg_io_workload_cpu_utilization"), allIntervals},
+	"performance_metrics_by_node": {"hardware", "node_id",
+		concat(ioPerformanceFields, "avg_io_size", "avg_io_workload_cpu_utilization", "avg_current_logins"), allIntervals},
+	"space_metrics_by_appliance":         {"appliance", "appliance_id", applianceSpaceFields, longIntervals},
+	"performance_metrics_by_volume":      {"volume", "volume_id", concat(ioPerformanceFields, "avg_io_size"), allIntervals},
+	"performance_metrics_by_vg":          {"volume_group_list_cma_view", "vg_id", concat(ioPerformanceFields, "avg_io_size"), allIntervals},
+	"performance_metrics_by_fe_fc_port":  {"fc_port", "fe_port_id", fcPortPerformanceFields, allIntervals},
+	"performance_metrics_by_fe_eth_port": {"eth_port", "fe_port_id", ethPortPerformanceFields, allIntervals},
+	"performance_metrics_by_nas_server":  {"nas_server", "nas_server_id", concat(ioPerformanceFields, "avg_size"), longIntervals},
+	"performance_metrics_by_file_system": {"file_system", "file_system_id",
+		concat(ioPerformanceFields, filesystemPerformanceFields...), longIntervals},
+	"space_metrics_by_file_system": {"file_system", "file_system_id",
+		[]string{"logical_provisioned", "logical_used", "thin_savings"}, longIntervals},
+	"wear_metrics_by_drive": {"hardware", "drive_id", []string{"percent_endurance_remaining"}, longIntervals},
+}
+
+// intervalDurations The time between two samples of each metrics/generate interval
+var intervalDurations = map[string]time.Duration{
+	"Twenty_Sec": 20 * time.Second,
+	"Five_Mins":  5 * time.Minute,
+	"One_Hour":   time.Hour,
+	"One_Day":    24 * time.Hour,
+}
+
+// Generate Build the objects of the topology, the same topology always gives the same objects
+func Generate(topology Topology) *Fixtures {
+	var (
+		appliances   []map[string]interface{}
+		hardware     []map[string]interface{}
+		ethPorts     []map[string]interface{}
+		fcPorts      []map[string]interface{}
+		volumes      []map[string]interface{}
+		volumeGroups []map[string]interface{}
+		nasServers   []map[string]interface{}
+		fileSystems  []map[string]interface{}
+	)
+	clusters := []map[string]interface{}{{
+		"id":                  "0",
+		"global_id":           "PS000000000001",
+		"name":                "fake-cluster",
+		"management_address":  "192.0.2.1",
+		"master_appliance_id": "A1",
+		"state":               "Configured",
+		"appliance_count":     topology.Appliances,
+		"physical_mtu":        1500,
+	}}
+	for a := 1; a <= topology.Appliances; a++ {
+		applianceID := fmt.Sprintf("A%d", a)
+		appliances = append(appliances, map[string]interface{}{
+			"id":          applianceID,
+			"name":        fmt.Sprintf("fake-appliance-%d", a),
+			"service_tag": fmt.Sprintf("TAG%04d", a),
+			"model":       "PowerStore 1000T",
+		})
+		for n := 1; n <= 2; n++ {
+			hardware = append(hardware, map[string]interface{}{
+				"id":              fmt.Sprintf("%s-node-%d", applianceID, n),
+				"name":            fmt.Sprintf("BaseEnclosure-NodeC%d", n),
+				"type":            "Node",
+				"appliance_id":    applianceID,
+				"parent_id":       nil,
+				"serial_number":   fmt.Sprintf("SN%s%d", applianceID, n),
+				"lifecycle_state": "Healthy",
+				"slot":            n - 1,
+				"extra_details":   map[string]interface{}{"cpu_model": "Intel(R) Xeon(R) Silver 4108 CPU @ 1.80GHz"},
+			})
+		}
+		for _, component := range []string{"Fan", "Power_Supply", "Battery"} {
+			hardware = append(hardware, map[string]interface{}{
+				"id":              fmt.Sprintf("%s-%s-1", applianceID, strings.ToLower(component)),
+				"name":            "BaseEnclosure-" + component + "-0",
+				"type":            component,
+				"appliance_id":    applianceID,
+				"parent_id":       applianceID + "-node-1",
+				"serial_number":   nil,
+				"lifecycle_state": "Healthy",
+				"slot":            0,
+			})
+		}
+		for d := 1; d <= topology.Drives; d++ {
+			hardware = append(hardware, map[string]interface{}{
+				"id":              fmt.Sprintf("%s-drive-%d", applianceID, d),
+				"name":            fmt.Sprintf("BaseEnclosure-Drive-%d", d-1),
+				"type":            "Drive",
+				"appliance_id":    applianceID,
+				"parent_id":       applianceID + "-enclosure",
+				"serial_number":   fmt.Sprintf("DRV%s%03d", applianceID, d),
+				"lifecycle_state": "Healthy",
+				"slot":            d - 1,
+				"extra_details": map[string]interface{}{
+					"drive_type":        "NVMe_SSD",
+					"size":              1920383410176,
+					"encryption_status": "Encrypted",
+					"firmware_version":  "2.1.0.0",
+				},
+			})
+		}
+		for p := 1; p <= topology.EthPorts; p++ {
+			ethPorts = append(ethPorts, map[string]interface{}{
+				"id":               fmt.Sprintf("%s-eth-%d", applianceID, p),
+				"name":             fmt.Sprintf("BaseEnclosure-NodeA-IoModule0-FEPort%d", p-1),
+				"appliance_id":     applianceID,
+				"node_id":          fmt.Sprintf("%s-node-%d", applianceID, 1+(p-1)%2),
+				"is_link_up":       true,
+				"current_speed":    "25_Gbps",
+				"requested_speed":  "Auto",
+				"supported_speeds": []string{"Auto", "10_Gbps", "25_Gbps"},
+				"current_mtu":      1500,
+			})
+		}
+		for p := 1; p <= topology.FcPorts; p++ {
+			fcPorts = append(fcPorts, map[string]interface{}{
+				"id":               fmt.Sprintf("%s-fc-%d", applianceID, p),
+				"name":             fmt.Sprintf("BaseEnclosure-NodeA-IoModule1-FEPort%d", p-1),
+				"appliance_id":     applianceID,
+				"node_id":          fmt.Sprintf("%s-node-%d", applianceID, 1+(p-1)%2),
+				"is_link_up":       p%2 == 1,
+				"current_speed":    "32_Gbps",
+				"requested_speed":  "Auto",
+				"supported_speeds": []string{"Auto", "8_Gbps", "16_Gbps", "32_Gbps"},
+				"wwn":              fmt.Sprintf("58:cc:f0:90:4d:2%d:0%d:%02x", a, p, p),
+			})
+		}
+		for v := 1; v <= topology.Volumes; v++ {
+			volumes = append(volumes, map[string]interface{}{
+				"id":                   fmt.Sprintf("%s-volume-%d", applianceID, v),
+				"name":                 fmt.Sprintf("volume-%s-%d", applianceID, v),
+				"appliance_id":         applianceID,
+				"type":                 "Primary",
+				"state":                "Ready",
+				"size":                 v * 107374182400,
+				"logical_used":         v * 1073741824,
+				"wwn":                  fmt.Sprintf("naa.68ccf09800%s%08d", strings.ToLower(applianceID), v),
+				"protection_policy_id": nil,
+			})
+		}
+		for g := 1; g <= topology.VolumeGroups; g++ {
+			volumeGroups = append(volumeGroups, map[string]interface{}{
+				"id":                        fmt.Sprintf("%s-vg-%d", applianceID, g),
+				"name":                      fmt.Sprintf("vg-%s-%d", applianceID, g),
+				"appliance_ids":             []string{applianceID},
+				"logical_provisioned":       g * 214748364800,
+				"logical_used":              g * 2147483648,
+				"is_write_order_consistent": true,
+			})
+		}
+	}
+	for n := 1; n <= topology.NasServers; n++ {
+		nasID := fmt.Sprintf("nas-%d", n)
+		nasServers = append(nasServers, map[string]interface{}{
+			"id":                 nasID,
+			"name":               fmt.Sprintf("fake-nas-%d", n),
+			"operational_status": "Started",
+			"current_node_id":    "A1-node-1",
+		})
+		for f := 1; f <= topology.FileSystems; f++ {
+			fileSystems = append(fileSystems, map[string]interface{}{
+				"id":              fmt.Sprintf("%s-fs-%d", nasID, f),
+				"name":            fmt.Sprintf("fs-%d-%d", n, f),
+				"nas_server_id":   nasID,
+				"filesystem_type": "Primary",
+			})
+		}
+	}
+	return &Fixtures{
+		Collections: map[string][]map[string]interface{}{
+			"cluster":                    clusters,
+			"appliance":                  appliances,
+			"hardware":                   hardware,
+			"eth_port":                   ethPorts,
+			"fc_port":                    fcPorts,
+			"volume":                     volumes,
+			"volume_list_cma_view":       volumes,
+			"volume_group_list_cma_view": volumeGroups,
+			"nas_server":                 nasServers,
+			"nas_server_list_cma_view":   nasServers,
+			"file_system":                fileSystems,
+		},
+		Samples: topology.Samples,
+	}
+}
+
+// Lookup Returns the object of the resource with the id
+func (f *Fixtures) Lookup(resource, id string) (map[string]interface{}, bool) {
+	for _, object := range f.Collections[resource] {
+		if object["id"] == id {
+			return object, true
+		}
+	}
+	return nil, false
+}
+
+// GenerateSamples Returns the samples of metrics/generate for the entity, oldest first.
+// The newest sample is at now truncated to the interval and the values only depend on the entity id, the field and the age of the sample.
+func (f *Fixtures) GenerateSamples(entity, id, interval string, now time.Time) ([]map[string]interface{}, error) {
+	definition, ok := sampleEntities[entity]
+	if !ok {
+		return nil, fmt.Errorf("unsupported entity %q", entity)
+	}
+	step, ok := intervalDurations[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+	if !contains(definition.intervals, interval) {
+		return nil, fmt.Errorf("interval %q is not supported by %s", interval, entity)
+	}
+	object, ok := f.Lookup(definition.resource, id)
+	if !ok {
+		return nil, fmt.Errorf("%s %q not found", definition.resource, id)
+	}
+	newest := now.UTC().Truncate(step)
+	samples := make([]map[string]interface{}, 0, f.Samples)
+	for age := f.Samples - 1; age >= 0; age-- {
+		sample := map[string]interface{}{
+			"entity":           entity,
+			definition.idField: id,
+			"appliance_id":     applianceOf(definition.resource, object),
+			"timestamp":        newest.Add(-time.Duration(age) * step).Format(time.RFC3339),
+			"repeat_count":     1,
+		}
+		for _, field := range definition.fields {
+			sample[field] = sampleValue(id, field, age)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// applianceOf Returns the appliance of an object
+func applianceOf(resource string, object map[string]interface{}) string {
+	if resource == "appliance" {
+		return object["id"].(string)
+	}
+	if id, ok := object["appliance_id"].(string); ok {
+		return id
+	}
+	switch ids := object["appliance_ids"].(type) {
+	case []string:
+		if len(ids) > 0 {
+			return ids[0]
+		}
+	case []interface{}:
+		if len(ids) > 0 {
+			id, _ := ids[0].(string)
+			return id
+		}
+	}
+	return "A1"
+}
+
+// sampleValue A value between 0 and 1000 with two decimals derived from the entity id, the field and the age of the sample
+func sampleValue(id, field string, age int) float64 {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s/%s/%d", id, field, age)
+	return float64(hash.Sum32()%100000) / 100
+}
+
+// concat Returns the fields followed by the more fields, in a new slice
+func concat(fields []string, more ...string) []string {
+	return append(append([]string{}, fields...), more...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package powerstorefake A fake powerstore rest api serving generated fixtures, to test the exporter without an array
+package powerstorefake
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"powerstore-metrics-exporter/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// User and Password The credentials accepted by login_session
+	User     = "admin"
+	Password = "Password123!"
+
+	apiPrefix       = "/api/rest/"
+	defaultPageSize = 2000
+)
+
+// Server A fake powerstore serving login_session, logout, the resource collections and metrics/generate over https
+type Server struct {
+	server   *httptest.Server
+	fixtures *Fixtures
+
+	lock     sync.Mutex
+	pageSize int
+	now      func() time.Time
+	sessions map[string]string
+	faults   map[string][]int
+	requests map[string]int
+	logins   int
+}
+
+// NewServer Start a fake powerstore serving the fixtures, it must be closed after use
+func NewServer(fixtures *Fixtures) *Server {
+	s := &Server{
+		fixtures: fixtures,
+		pageSize: defaultPageSize,
+		now:      time.Now,
+		sessions: make(map[string]string),
+		faults:   make(map[string][]int),
+		requests: make(map[string]int),
+	}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close Stop the fake powerstore
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Address Returns the host:port of the fake powerstore, the ip of its storage config
+func (s *Server) Address() string {
+	return s.server.Listener.Addr().String()
+}
+
+// Storage Returns the storage config of the fake powerstore, its certificate is pinned by fingerprint
+func (s *Server) Storage() utils.Storage {
+	sum := sha256.Sum256(s.server.Certificate().Raw)
+	return utils.Storage{
+		Ip:       s.Address(),
+		User:     User,
+		Password: Password,
+		Version:  "v3",
+		TLS:      utils.TLS{Fingerprint: hex.EncodeToString(sum[:])},
+	}
+}
+
+// SetPageSize Return the collections in pages of size items, like a powerstore capping the limit of a query
+func (s *Server) SetPageSize(size int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pageSize = size
+}
+
+// SetClock Replace the clock the metrics/generate samples are generated at
+func (s *Server) SetClock(now func() time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.now = now
+}
+
+// ExpireSessions End all the sessions, the next requests get 401 until the clients login again
+func (s *Server) ExpireSessions() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sessions = make(map[string]string)
+}
+
+// Fail Answer the next times requests to the resource with the status, e.g. metrics/generate or volume
+func (s *Server) Fail(resource string, status, times int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := 0; i < times; i++ {
+		s.faults[resource] = append(s.faults[resource], status)
+	}
+}
+
+// Requests Returns the number of requests received for the resource, including the failed ones
+func (s *Server) Requests(resource string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[resource]
+}
+
+// Logins Returns the number of successful logins
+func (s *Server) Logins() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.logins
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeError(w, http.StatusNotFound, "unknown path "+r.URL.Path)
+		return
+	}
+	resource := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	if status, ok := s.record(resource); ok {
+		writeError(w, status, "injected fault")
+		return
+	}
+	if resource == "login_session" {
+		s.login(w, r)
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "the session is expired or invalid")
+		return
+	}
+	switch {
+	case resource == "logout" && r.Method == http.MethodPost:
+		s.logout(r)
+		w.WriteHeader(http.StatusNoContent)
+	case resource == "metrics/generate" && r.Method == http.MethodPost:
+		s.generate(w, r)
+	case r.Method == http.MethodGet:
+		s.collection(w, r, resource)
+	default:
+		writeError(w, http.StatusNotFound, "unknown resource "+resource)
+	}
+}
+
+// record Count the request and return the injected fault of the resource, if any
+func (s *Server) record(resource string) (int, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[resource]++
+	faults := s.faults[resource]
+	if len(faults) == 0 {
+		return 0, false
+	}
+	s.faults[resource] = faults[1:]
+	return faults[0], true
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != User || password != Password {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	token, cookie := randomID(), randomID()
+	s.lock.Lock()
+	s.sessions[token] = cookie
+	s.logins++
+	s.lock.Unlock()
+	w.Header().Set("DELL-EMC-TOKEN", token)
+	http.SetCookie(w, &http.Cookie{Name: "auth_cookie", Value: cookie, Path: "/", Secure: true, HttpOnly: true})
+	writeJSON(w, http.StatusOK, []map[string]interface{}{{"id": "fake-session", "user": user}})
+}
+
+// authorized Whether the request carries the token and cookie of a session
+func (s *Server) authorized(r *http.Request) bool {
+	cookie, err := r.Cookie("auth_cookie")
+	if err != nil {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	expected, ok := s.sessions[r.Header.Get("DELL-EMC-TOKEN")]
+	return ok && expected == cookie.Value
+}
+
+func (s *Server) logout(r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sessions, r.Header.Get("DELL-EMC-TOKEN"))
+}
+
+// collection Serve a collection query: the eq filters and select of the query are applied
+// and the items beyond the limit or the page size are left to the next pages, see Content-Range
+func (s *Server) collection(w http.ResponseWriter, r *http.Request, resource string) {
+	objects, ok := s.fixtures.Collections[resource]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown resource "+resource)
+		return
+	}
+	query := r.URL.Query()
+	objects = filter(objects, query)
+	s.lock.Lock()
+	limit := s.pageSize
+	s.lock.Unlock()
+	if value, err := strconv.Atoi(query.Get("limit")); err == nil && value > 0 && value < limit {
+		limit = value
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	total := len(objects)
+	if total == 0 || offset >= total {
+		w.Header().Set("Content-Range", fmt.Sprintf("*/%d", total))
+		writeJSON(w, http.StatusOK, []interface{}{})
+		return
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	page := project(objects[offset:end], query.Get("select"))
+	w.Header().Set("Content-Range", fmt.Sprintf("%d-%d/%d", offset, end-1, total))
+	status := http.StatusOK
+	if offset > 0 || end < total {
+		status = http.StatusPartialContent
+	}
+	writeJSON(w, status, page)
+}
+
+// generateRequest The body of a metrics/generate request
+type generateRequest struct {
+	Entity   string `json:"entity"`
+	EntityID string `json:"entity_id"`
+	Interval string `json:"interval"`
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	var body generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	s.lock.Lock()
+	now := s.now()
+	s.lock.Unlock()
+	samples, err := s.fixtures.GenerateSamples(body.Entity, body.EntityID, body.Interval, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, samples)
+}
+
+// filter Returns the objects matching the eq filters of the query, e.g. type=eq.Drive
+func filter(objects []map[string]interface{}, query url.Values) []map[string]interface{} {
+	result := objects
+	for field, values := range query {
+		for _, value := range values {
+			if !strings.HasPrefix(value, "eq.") {
+				continue
+			}
+			expected := strings.TrimPrefix(value, "eq.")
+			var matching []map[string]interface{}
+			for _, object := range result {
+				if fmt.Sprint(object[field]) == expected {
+					matching = append(matching, object)
+				}
+			}
+			result = matching
+		}
+	}
+	return result
+}
+
+// project Returns the fields of the select of the query, all of them for * or no select
+func project(objects []map[string]interface{}, selection string) []map[string]interface{} {
+	if selection == "" || selection == "*" {
+		return objects
+	}
+	fields := strings.Split(selection, ",")
+	result := make([]map[string]interface{}, 0, len(objects))
+	for _, object := range objects {
+		projected := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				projected[field] = value
+			}
+		}
+		result = append(result, projected)
+	}
+	return result
+}
+
+// writeError Answer with the error body of the powerstore rest api
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"messages": []map[string]interface{}{{
+			"code":         "0xE0F010010001",
+			"severity":     "Error",
+			"message_l10n": message,
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
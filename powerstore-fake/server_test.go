@@ -0,0 +1,231 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package powerstorefake_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"powerstore-metrics-exporter/collector/client"
+	powerstorefake "powerstore-metrics-exporter/powerstore-fake"
+	"powerstore-metrics-exporter/utils"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitScheduler(10)
+	os.Exit(m.Run())
+}
+
+// newClient Start a fake powerstore of the topology and login to it
+func newClient(t *testing.T, topology powerstorefake.Topology) (*powerstorefake.Server, *client.Client) {
+	t.Helper()
+	fake := powerstorefake.NewServer(powerstorefake.Generate(topology))
+	t.Cleanup(fake.Close)
+	storage := fake.Storage()
+	storage.Retry = utils.Retry{InitialBackoff: 0.01, MaxBackoff: 0.01}
+	api, err := client.NewClient(storage, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("login to the fake powerstore: %v", err)
+	}
+	return fake, api
+}
+
+func TestCollections(t *testing.T) {
+	fake, api := newClient(t, powerstorefake.DefaultTopology())
+	ctx := context.Background()
+	clusters, err := api.GetCluster(ctx)
+	if err != nil || len(clusters) != 1 || clusters[0].State != "Configured" {
+		t.Fatalf("GetCluster() = %+v, %v", clusters, err)
+	}
+	drives, err := api.GetHardware(ctx, "Drive")
+	if err != nil || len(drives) != 2 {
+		t.Fatalf("GetHardware(Drive) = %+v, %v", drives, err)
+	}
+	for _, drive := range drives {
+		if drive.Type != "Drive" || drive.ExtraDetails.Size == nil {
+			t.Errorf("drive %+v is not a drive with a size", drive)
+		}
+	}
+	ports, err := api.GetPort(ctx, "fc_port")
+	if err != nil || len(ports) != 2 || ports[0].CurrentSpeed == nil {
+		t.Fatalf("GetPort(fc_port) = %+v, %v", ports, err)
+	}
+	if fake.Logins() != 1 {
+		t.Errorf("Logins() = %d, want 1", fake.Logins())
+	}
+}
+
+func TestPagination(t *testing.T) {
+	topology := powerstorefake.DefaultTopology()
+	topology.Volumes = 5
+	fake, api := newClient(t, topology)
+	fake.SetPageSize(2)
+	volumes, err := api.GetVolume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 5 {
+		t.Fatalf("GetVolume() returned %d volumes, want 5", len(volumes))
+	}
+	seen := map[string]bool{}
+	for _, volume := range volumes {
+		seen[volume.ID] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("GetVolume() returned duplicated volumes: %+v", volumes)
+	}
+	if got := fake.Requests("volume_list_cma_view"); got != 3 {
+		t.Errorf("volume_list_cma_view requests = %d, want 3 pages", got)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	fake, api := newClient(t, powerstorefake.DefaultTopology())
+	ctx := context.Background()
+	fake.ExpireSessions()
+	if _, err := api.GetAppliance(ctx); err != nil {
+		t.Fatalf("GetAppliance() after the session expired: %v", err)
+	}
+	if fake.Logins() != 2 {
+		t.Errorf("Logins() = %d, want a re-login", fake.Logins())
+	}
+	if err := api.Logout(ctx); err != nil {
+		t.Fatalf("Logout(): %v", err)
+	}
+	if fake.Requests("logout") != 1 {
+		t.Errorf("logout requests = %d, want 1", fake.Requests("logout"))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	fake, api := newClient(t, powerstorefake.DefaultTopology())
+	ctx := context.Background()
+
+	fake.Fail("cluster", http.StatusInternalServerError, 1)
+	_, err := api.GetCluster(ctx)
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusInternalServerError {
+		t.Fatalf("GetCluster() error = %v, want a 500 status error", err)
+	}
+
+	fake.Fail("cluster", http.StatusServiceUnavailable, 1)
+	before := fake.Requests("cluster")
+	if _, err := api.GetCluster(ctx); err != nil {
+		t.Fatalf("GetCluster() after a 503: %v", err)
+	}
+	if got := fake.Requests("cluster") - before; got != 2 {
+		t.Errorf("cluster requests = %d, want the 503 to be retried once", got)
+	}
+
+	if _, err := api.GetMetricVolume(ctx, "no-such-volume", client.IntervalFiveMins); err == nil {
+		t.Error("GetMetricVolume() of an unknown volume succeeded")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	fake, api := newClient(t, powerstorefake.DefaultTopology())
+	now := time.Date(2024, 5, 1, 10, 7, 30, 0, time.UTC)
+	fake.SetClock(func() time.Time { return now })
+	samples, err := api.GetMetricVolume(context.Background(), "A1-volume-1", client.IntervalFiveMins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("GetMetricVolume() returned %d samples, want 3", len(samples))
+	}
+	newest := samples[len(samples)-1]
+	if want := time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC); !newest.SampleTime().Equal(want) {
+		t.Errorf("newest sample at %v, want %v", newest.SampleTime(), want)
+	}
+	if !samples[0].SampleTime().Before(newest.SampleTime()) {
+		t.Errorf("samples are not oldest first: %v, %v", samples[0].SampleTime(), newest.SampleTime())
+	}
+	if newest.VolumeID != "A1-volume-1" || newest.ApplianceID != "A1" || newest.AvgLatency == nil || newest.AvgIoSize == nil {
+		t.Errorf("newest sample %+v misses fields", newest)
+	}
+
+	again, err := api.GetMetricVolume(context.Background(), "A1-volume-1", client.IntervalFiveMins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *again[2].AvgLatency != *newest.AvgLatency {
+		t.Errorf("the samples are not deterministic: %v != %v", *again[2].AvgLatency, *newest.AvgLatency)
+	}
+}
+
+// nilFields Returns the nil pointer fields of the model, including the embedded ones
+func nilFields(value reflect.Value) []string {
+	var fields []string
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		switch {
+		case field.Anonymous && field.Type.Kind() == reflect.Struct:
+			fields = append(fields, nilFields(value.Field(i))...)
+		case field.Type.Kind() == reflect.Ptr && value.Field(i).IsNil():
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// TestModelsDecodeFixtures The fixtures carry the field names of the powerstore rest api, a field of a model
+// decoded under another name stays nil
+func TestModelsDecodeFixtures(t *testing.T) {
+	_, api := newClient(t, powerstorefake.DefaultTopology())
+	ctx := context.Background()
+	interval := client.IntervalFiveMins
+	fetches := map[string]func() (interface{}, error){
+		"appliance performance": func() (interface{}, error) { return api.GetPerf(ctx, "A1", interval) },
+		"appliance space":       func() (interface{}, error) { return api.GetCap(ctx, "A1", interval) },
+		"node":                  func() (interface{}, error) { return api.GetMetricNode(ctx, "A1-node-1", interval) },
+		"volume":                func() (interface{}, error) { return api.GetMetricVolume(ctx, "A1-volume-1", interval) },
+		"volume group":          func() (interface{}, error) { return api.GetMetricVg(ctx, "A1-vg-1", interval) },
+		"fc port":               func() (interface{}, error) { return api.GetMetricFcPort(ctx, "A1-fc-1", interval) },
+		"eth port":              func() (interface{}, error) { return api.GetMetricEthPort(ctx, "A1-eth-1", interval) },
+		"nas server":            func() (interface{}, error) { return api.GetMetricByNas(ctx, "nas-1", interval) },
+		"file system":           func() (interface{}, error) { return api.GetMetricsFilesystem(ctx, "nas-1-fs-1", interval) },
+		"file system space":     func() (interface{}, error) { return api.GetFilesystemCap(ctx, "nas-1-fs-1", interval) },
+		"drive wear":            func() (interface{}, error) { return api.GetWearMetricByDrive(ctx, "A1-drive-1", interval) },
+		"ports":                 func() (interface{}, error) { return api.GetPort(ctx, "eth_port") },
+		"drives":                func() (interface{}, error) { return api.GetHardware(ctx, "Drive") },
+		"volumes":               func() (interface{}, error) { return api.GetVolume(ctx) },
+		"volume groups":         func() (interface{}, error) { return api.GetVolumeGroup(ctx) },
+		"appliances":            func() (interface{}, error) { return api.GetAppliance(ctx) },
+		"nas servers":           func() (interface{}, error) { return api.GetNas(ctx) },
+	}
+	for name, fetch := range fetches {
+		list, err := fetch()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		items := reflect.ValueOf(list)
+		if items.Len() == 0 {
+			t.Errorf("%s: nothing returned", name)
+			continue
+		}
+		if missing := nilFields(items.Index(0)); len(missing) > 0 {
+			t.Errorf("%s: %v are not decoded", name, missing)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package route
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	powerstorefake "powerstore-metrics-exporter/powerstore-fake"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden with the scraped metrics")
+
+// goldenClock The time the fake powerstore generates its samples at
+var goldenClock = time.Date(2024, 5, 1, 10, 7, 30, 0, time.UTC)
+
+// volatileMetrics The series whose value depends on the time of the test run, their value is replaced by 0
+var volatileMetrics = regexp.MustCompile(`^((?:powerstore_scrape_collector_duration_seconds|powerstore_sample_age_seconds)(?:\{.*\})?) \S+$`)
+
+// TestGoldenMetrics Compare the metrics of every category of the fake powerstore to testdata/golden/{category}.prom,
+// run go test ./route -run TestGolden -update to accept the changes
+func TestGoldenMetrics(t *testing.T) {
+	e := newGoldenExporter(t)
+	for category := range categories {
+		category := category
+		t.Run(category, func(t *testing.T) {
+			checkGolden(t, category, normalizeMetrics(e.scrape(t, category), e.storage.Ip))
+		})
+	}
+}
+
+// TestGoldenEndpoints Compare the metrics of the endpoints serving several categories at once to testdata/golden/{name}.prom
+func TestGoldenEndpoints(t *testing.T) {
+	e := newGoldenExporter(t)
+	ip := e.storage.Ip
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"metrics_all", "/metrics/" + ip},
+		{"metrics_collect", "/metrics/" + ip + "?collect[]=cluster&collect[]=port"},
+		{"probe_module", "/probe?target=" + ip + "&module=cluster,volumeGroup"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			status, body := e.get(t, test.path)
+			if status != http.StatusOK {
+				t.Fatalf("GET %s: %d %s", test.path, status, body)
+			}
+			checkGolden(t, test.name, normalizeMetrics(body, ip))
+		})
+	}
+
+	// the probe of every category serves the same metrics as the combined endpoint
+	_, all := e.get(t, "/metrics/"+ip)
+	if _, probe := e.get(t, "/probe?target="+ip); normalizeMetrics(probe, ip) != normalizeMetrics(all, ip) {
+		t.Errorf("the probe without module differs from /metrics/%s:\n%s", ip, diffLines(normalizeMetrics(all, ip), normalizeMetrics(probe, ip)))
+	}
+
+	rejected := []string{
+		"/metrics/" + ip + "?collect[]=cluster&collect[]=unknown",
+		"/probe?target=" + ip + "&module=unknown",
+		"/probe?target=192.0.2.99",
+		"/probe",
+	}
+	for _, path := range rejected {
+		if status, body := e.get(t, path); status != http.StatusBadRequest {
+			t.Errorf("GET %s: %d %s, want 400", path, status, body)
+		}
+	}
+}
+
+// newGoldenExporter Serve the metrics of a fake powerstore of the default topology generating its samples at goldenClock
+func newGoldenExporter(t *testing.T) *exporter {
+	t.Helper()
+	fake := powerstorefake.NewServer(powerstorefake.Generate(powerstorefake.DefaultTopology()))
+	t.Cleanup(fake.Close)
+	fake.SetClock(func() time.Time { return goldenClock })
+	return newExporter(t, fake)
+}
+
+// checkGolden Compare the metrics to testdata/golden/{name}.prom, or rewrite it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".prom")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v, run go test ./route -run TestGolden -update to create it", err)
+	}
+	if diff := diffLines(string(want), got); diff != "" {
+		t.Errorf("the %s metrics differ from %s (-want +got):\n%s", name, path, diff)
+	}
+}
+
+// normalizeMetrics Replace the address of the fake powerstore and the values depending on the time of the run
+func normalizeMetrics(metrics, ip string) string {
+	metrics = strings.ReplaceAll(metrics, `IP="`+ip+`"`, `IP="powerstore"`)
+	lines := strings.Split(metrics, "\n")
+	for i, line := range lines {
+		lines[i] = volatileMetrics.ReplaceAllString(line, "$1 0")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffLines Returns the lines only in want prefixed by - and the lines only in got prefixed by +,
+// in the order of the exposition, empty when both have the same lines
+func diffLines(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// longest common subsequence, the expositions are sorted so the differences stay next to each other
+	lcs := make([][]int, len(wantLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(gotLines)+1)
+	}
+	for i := len(wantLines) - 1; i >= 0; i-- {
+		for j := len(gotLines) - 1; j >= 0; j-- {
+			switch {
+			case wantLines[i] == gotLines[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(wantLines) || j < len(gotLines) {
+		switch {
+		case i < len(wantLines) && j < len(gotLines) && wantLines[i] == gotLines[j]:
+			i++
+			j++
+		case j == len(gotLines) || (i < len(wantLines) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("- " + wantLines[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + gotLines[j] + "\n")
+			j++
+		}
+	}
+	return diff.String()
+}
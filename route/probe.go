@@ -0,0 +1,51 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package route
+
+import (
+	"net/http"
+	"powerstore-metrics-exporter/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/log"
+)
+
+// probeHandler Serves the categories listed in the module parameter of the powerstore given by the target parameter,
+// all the categories are served when module is empty
+func probeHandler(powerstores map[string]*powerstore, logger log.Logger) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		target := context.Query("target")
+		array, ok := powerstores[target]
+		if !ok {
+			context.String(http.StatusBadRequest, "unknown target %q", target)
+			return
+		}
+		var modules []string
+		if module := context.Query("module"); module != "" {
+			modules = strings.Split(module, ",")
+		}
+		ctx, cancel := scrapeContext(context.Request)
+		defer cancel()
+		gatherer, commit, err := array.gatherer(ctx, modules)
+		if err != nil {
+			context.String(http.StatusBadRequest, "%s", err)
+			return
+		}
+		utils.PrometheusHandler(gatherer, commit, logger)(context)
+	}
+}
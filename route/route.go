@@ -17,85 +17,319 @@
 package route
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"powerstore-metrics-exporter/collector/client"
 	"powerstore-metrics-exporter/collector/generalCollector"
 	"powerstore-metrics-exporter/utils"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 )
 
-func Run(config *utils.Config, logger log.Logger) {
+// categories The collectors behind each /metrics/{ip}/{category} endpoint
+var categories = map[string]func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector{
+	"cluster": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{generalCollector.NewClusterCollector(api, logger)}
+	},
+	"port": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewPortCollector(api, logger),
+			generalCollector.NewMetricFcPortCollector(api, config, logger),
+			generalCollector.NewMetricEthPortCollector(api, config, logger),
+		}
+	},
+	"file": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewFileCollector(api, config, logger),
+			generalCollector.NewMetricFilesystemCollector(api, config, logger),
+		}
+	},
+	"hardware": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewHardwareCollector(api, logger),
+			generalCollector.NewWearMetricCollector(api, config, logger),
+		}
+	},
+	"volume": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewVolumeCollector(api, logger),
+			generalCollector.NewMetricVolumeCollector(api, config, logger),
+		}
+	},
+	"appliance": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewApplianceCollector(api, logger),
+			generalCollector.NewMetricApplianceCollector(api, config, logger),
+			generalCollector.NewMetricNodeCollector(api, config, logger),
+		}
+	},
+	"nas": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewNasCollector(api, logger),
+			generalCollector.NewMetricNasCollector(api, config, logger),
+		}
+	},
+	"volumeGroup": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{
+			generalCollector.NewVolumeGroupCollector(api, logger),
+			generalCollector.NewMetricVgCollector(api, config, logger),
+		}
+	},
+	"capacity": func(api client.PowerStoreAPI, config utils.Metrics, logger log.Logger) []prometheus.Collector {
+		return []prometheus.Collector{generalCollector.NewCapacityCollector(api, config, logger)}
+	},
+}
+
+// powerstore The collectors of a configured powerstore by category, and their snapshots in polling mode
+type powerstore struct {
+	collectors map[string][]prometheus.Collector
+	snapshots  map[string]*utils.Snapshot
+}
+
+// gatherer Returns the gatherer of the categories, all the categories when none is given, and the commit to call
+// once its metrics are written. The powerstore requests of the collection are cancelled with ctx,
+// snapshots are polled in the background instead.
+func (p *powerstore) gatherer(ctx context.Context, names []string) (prometheus.Gatherer, func(), error) {
+	selected := map[string]bool{}
+	for _, name := range names {
+		if _, ok := p.collectors[name]; !ok {
+			return nil, nil, fmt.Errorf("unknown collector %q", name)
+		}
+		selected[name] = true
+	}
+	if len(selected) == 0 {
+		for name := range p.collectors {
+			selected[name] = true
+		}
+	}
+	if len(p.snapshots) > 0 {
+		var gatherers []prometheus.Gatherer
+		for name := range selected {
+			gatherers = append(gatherers, p.snapshots[name])
+		}
+		return utils.MergeGatherers(gatherers...), nil, nil
+	}
+	var collectors []prometheus.Collector
+	for name := range selected {
+		collectors = append(collectors, p.collectors[name]...)
+	}
+	gatherer, commit := collect(ctx, collectors)
+	return gatherer, commit, nil
+}
+
+// collect Returns the gatherer of one collection of the collectors, whose powerstore requests are cancelled with ctx,
+// and the commit moving the gap free cursors once the gathered metrics are exposed
+func collect(ctx context.Context, collectors []prometheus.Collector) (prometheus.Gatherer, func()) {
+	exposition := utils.NewExposition()
+	ctx = utils.WithExposition(ctx, exposition)
+	registry := prometheus.NewPedanticRegistry()
+	for _, collector := range collectors {
+		registry.MustRegister(generalCollector.WithContext(ctx, collector))
+	}
+	return exposition.Gatherer(registry), exposition.Commit
+}
+
+// pollGatherer Returns the gatherer of the snapshot of the collectors, a snapshot is exposed once gathered
+func pollGatherer(ctx context.Context, collectors []prometheus.Collector) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		gatherer, commit := collect(ctx, collectors)
+		families, err := gatherer.Gather()
+		commit()
+		return families, err
+	})
+}
+
+// handler Serves the categories, or the ones of the collect[] parameter when no category is given
+func (p *powerstore) handler(logger log.Logger, names ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		selected := names
+		if len(selected) == 0 {
+			selected = context.QueryArray("collect[]")
+		}
+		ctx, cancel := scrapeContext(context.Request)
+		defer cancel()
+		gatherer, commit, err := p.gatherer(ctx, selected)
+		if err != nil {
+			context.String(http.StatusBadRequest, "%s", err)
+			return
+		}
+		utils.PrometheusHandler(gatherer, commit, logger)(context)
+	}
+}
+
+// scrapeTimeoutOffset The part of the prometheus scrape timeout left to send the response
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// scrapeContext Returns the context of a scrape, cancelled when prometheus closes the connection
+// or when the scrape timeout sent by prometheus in X-Prometheus-Scrape-Timeout-Seconds is reached
+func scrapeContext(request *http.Request) (context.Context, context.CancelFunc) {
+	header := request.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return context.WithCancel(request.Context())
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return context.WithCancel(request.Context())
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > 2*scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+	return context.WithTimeout(request.Context(), timeout)
+}
+
+// NewRouter Create the clients of the configured powerstores and the routes serving their metrics.
+// The returned shutdown stops the background loops, the inventory refresh and the polling,
+// waits for them to return and logs out of the powerstores.
+func NewRouter(config *utils.Config, logger log.Logger) (*gin.Engine, func(), error) {
+	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
-	gin.SetMode(gin.ReleaseMode)
+	if err := generalCollector.CheckIntervals(config.Metrics); err != nil {
+		return nil, nil, fmt.Errorf("metrics intervals config error: %w", err)
+	}
+	if err := config.Exporter.Polling.Check(); err != nil {
+		return nil, nil, fmt.Errorf("polling config error: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var loops sync.WaitGroup
+	background := func(loop func()) {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			loop()
+		}()
+	}
+	if config.Metrics.GapFree {
+		level.Warn(logger).Log("msg", "metrics.gapFree expects a single prometheus scraping each powerstore through one endpoint: "+
+			"the samples written to a scraper are not exposed again, another scraper or endpoint of the same powerstore misses them")
+	}
+	powerstores := make(map[string]*powerstore)
+	var clients []*client.Client
 	for _, storage := range config.StorageList {
+		storage.Traffic = config.Traffic
 		client, err := client.NewClient(storage, logger)
 		if err != nil {
 			level.Error(logger).Log("msg", "init Powerstore client error", "err", err, "ip", storage.Ip)
+			// the storage config is invalid, e.g. its tls config
+			if client == nil {
+				continue
+			}
 		}
 
-		client.InitModuleID(logger)
-
-		ClusterRegistry := prometheus.NewPedanticRegistry()
-		PortRegistry := prometheus.NewPedanticRegistry()
-		FileSystemRegistry := prometheus.NewPedanticRegistry()
-		HardwareRegistry := prometheus.NewPedanticRegistry()
-		VolumeRegistry := prometheus.NewPedanticRegistry()
-		ApplianceRegistry := prometheus.NewPedanticRegistry()
-		NasRegistry := prometheus.NewPedanticRegistry()
-		VolumeGroupRegistry := prometheus.NewPedanticRegistry()
-		CapacityRegistry := prometheus.NewPedanticRegistry()
-
-		ClusterRegistry.MustRegister(generalCollector.NewClusterCollector(client, logger))
-		PortRegistry.MustRegister(generalCollector.NewPortCollector(client, logger))
-		PortRegistry.MustRegister(generalCollector.NewMetricFcPortCollector(client, logger))
-		PortRegistry.MustRegister(generalCollector.NewMetricEthPortCollector(client, logger))
-		FileSystemRegistry.MustRegister(generalCollector.NewFileCollector(client, logger))
-		FileSystemRegistry.MustRegister(generalCollector.NewMetricFilesystemCollector(client, logger))
-		HardwareRegistry.MustRegister(generalCollector.NewHardwareCollector(client, logger))
-		HardwareRegistry.MustRegister(generalCollector.NewWearMetricCollector(client, logger))
-		VolumeRegistry.MustRegister(generalCollector.NewVolumeCollector(client, logger))
-		VolumeRegistry.MustRegister(generalCollector.NewMetricVolumeCollector(client, logger))
-		ApplianceRegistry.MustRegister(generalCollector.NewApplianceCollector(client, logger))
-		ApplianceRegistry.MustRegister(generalCollector.NewMetricApplianceCollector(client, logger))
-		NasRegistry.MustRegister(generalCollector.NewNasCollector(client, logger))
-		NasRegistry.MustRegister(generalCollector.NewMetricNasCollector(client, logger))
-		VolumeGroupRegistry.MustRegister(generalCollector.NewVolumeGroupCollector(client, logger))
-		VolumeGroupRegistry.MustRegister(generalCollector.NewMetricVgCollector(client, logger))
-		CapacityRegistry.MustRegister(generalCollector.NewCapacityCollector(client, logger))
+		clients = append(clients, client)
+		client.InitModuleID(ctx, logger)
+		background(func() {
+			client.RefreshModuleID(ctx, time.Duration(config.Exporter.InventoryInterval)*time.Second, logger)
+		})
 
+		array := &powerstore{
+			collectors: make(map[string][]prometheus.Collector),
+			snapshots:  make(map[string]*utils.Snapshot),
+		}
+		powerstores[storage.Ip] = array
+		for category, newCollectors := range categories {
+			array.collectors[category] = newCollectors(client, config.Metrics, logger)
+			// in polling mode the category is collected in the background and scrapes are served from the last snapshot
+			if config.Exporter.Polling.Enabled {
+				gatherer := pollGatherer(ctx, array.collectors[category])
+				snapshot := utils.NewSnapshot(gatherer, prometheus.Labels{"IP": storage.Ip, "category": category})
+				interval := config.Exporter.Polling.IntervalOf(category)
+				background(func() {
+					snapshot.Poll(ctx, interval, logger)
+				})
+				array.snapshots[category] = snapshot
+			}
+		}
 		metricsGroup := r.Group(fmt.Sprintf("/metrics/%s", storage.Ip))
 		{
-			metricsGroup.GET("cluster", utils.PrometheusHandler(ClusterRegistry, logger))
-			metricsGroup.GET("port", utils.PrometheusHandler(PortRegistry, logger))
-			metricsGroup.GET("file", utils.PrometheusHandler(FileSystemRegistry, logger))
-			metricsGroup.GET("hardware", utils.PrometheusHandler(HardwareRegistry, logger))
-			metricsGroup.GET("volume", utils.PrometheusHandler(VolumeRegistry, logger))
-			metricsGroup.GET("appliance", utils.PrometheusHandler(ApplianceRegistry, logger))
-			metricsGroup.GET("nas", utils.PrometheusHandler(NasRegistry, logger))
-			metricsGroup.GET("volumeGroup", utils.PrometheusHandler(VolumeGroupRegistry, logger))
-			metricsGroup.GET("capacity", utils.PrometheusHandler(CapacityRegistry, logger))
+			// every category in one response, e.g. /metrics/10.0.0.1?collect[]=volume&collect[]=hardware
+			metricsGroup.GET("", array.handler(logger))
+			for category := range categories {
+				metricsGroup.GET(category, array.handler(logger, category))
+			}
 		}
 		level.Info(logger).Log("msg", "The Powerstore is ready", "ip", storage.Ip)
 	}
 
+	// all the configured powerstores behind one endpoint, e.g. /probe?target=10.0.0.1&module=volume,hardware
+	r.GET("/probe", probeHandler(powerstores, logger))
+
 	// exporter Performance
 	r.GET("/performance", func(context *gin.Context) {
 		h := promhttp.Handler()
 		h.ServeHTTP(context.Writer, context.Request)
 	})
+	shutdown := func() {
+		cancel()
+		loops.Wait()
+		logout(clients, logger)
+	}
+	return r, shutdown, nil
+}
+
+func Run(config *utils.Config, logger log.Logger) {
+	r, shutdown, err := NewRouter(config, logger)
+	if err != nil {
+		level.Error(logger).Log("msg", "init exporter error", "err", err)
+		os.Exit(1)
+	}
 
 	httpPort := fmt.Sprintf(":%s", strconv.Itoa(config.Exporter.Port))
 	level.Info(logger).Log("msg", "~~~~~~~~~~~~~Start PowerStore Exporter~~~~~~~~~~~~~~")
 	level.Info(logger).Log("http-port", httpPort)
-	err := r.Run(httpPort)
-	if err != nil {
+	server := &http.Server{Addr: httpPort, Handler: r}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// closed once the scrapes in flight are drained, the sessions they use must not be ended before
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		level.Info(logger).Log("msg", "Shutting down PowerStore Exporter")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			level.Warn(logger).Log("msg", "http server shutdown error", "err", err)
+		}
+	}()
+	err = server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		<-drained
+	} else if err != nil {
 		level.Error(logger).Log("msg", "Service startup failed", "err", err)
 	}
+	shutdown()
+}
+
+// shutdownTimeout How long the scrapes in progress and the logouts may take on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// logout End the sessions of the clients on the powerstores
+func logout(clients []*client.Client, logger log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, api := range clients {
+		wg.Add(1)
+		go func(api *client.Client) {
+			defer wg.Done()
+			if err := api.Logout(ctx); err != nil {
+				level.Warn(logger).Log("msg", "logout error", "err", err, "ip", api.IP)
+			}
+		}(api)
+	}
+	wg.Wait()
 }
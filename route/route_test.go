@@ -0,0 +1,226 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	powerstorefake "powerstore-metrics-exporter/powerstore-fake"
+	"powerstore-metrics-exporter/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitScheduler(10)
+	os.Exit(m.Run())
+}
+
+// exporter An exporter serving the metrics of a fake powerstore
+type exporter struct {
+	server  *httptest.Server
+	storage utils.Storage
+}
+
+func newExporter(t *testing.T, fake *powerstorefake.Server) *exporter {
+	t.Helper()
+	return startExporter(t, fake.Storage(), utils.Traffic{})
+}
+
+// startExporter Serve the metrics of the storage, its requests are recorded or replayed as set by traffic
+func startExporter(t *testing.T, storage utils.Storage, traffic utils.Traffic) *exporter {
+	t.Helper()
+	storage.Retry = utils.Retry{Attempts: 2, InitialBackoff: 0.01, MaxBackoff: 0.01}
+	config := &utils.Config{
+		Exporter:    utils.Exporter{ReqLimit: 10, InventoryInterval: 3600},
+		StorageList: []utils.Storage{storage},
+		Traffic:     traffic,
+	}
+	router, shutdown, err := NewRouter(config, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		server.Close()
+		shutdown()
+	})
+	return &exporter{server: server, storage: storage}
+}
+
+// scrape Returns the metrics of the category of the fake powerstore
+func (e *exporter) scrape(t *testing.T, category string) string {
+	t.Helper()
+	status, body := e.get(t, "/metrics/"+e.storage.Ip+"/"+category)
+	if status != http.StatusOK {
+		t.Fatalf("scrape %s: %d %s", category, status, body)
+	}
+	return body
+}
+
+// get Returns the status and body of the response of the exporter to the path with its query
+func (e *exporter) get(t *testing.T, path string) (int, string) {
+	t.Helper()
+	response, err := http.Get(e.server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, string(body)
+}
+
+// collectorSuccess Returns the powerstore_scrape_collector_success lines of the metrics
+func collectorSuccess(metrics string) []string {
+	var lines []string
+	for _, line := range strings.Split(metrics, "\n") {
+		if strings.HasPrefix(line, "powerstore_scrape_collector_success{") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestEndToEnd(t *testing.T) {
+	fake := powerstorefake.NewServer(powerstorefake.Generate(powerstorefake.DefaultTopology()))
+	t.Cleanup(fake.Close)
+	e := newExporter(t, fake)
+	ip := `IP="` + e.storage.Ip + `"`
+
+	expected := map[string][]string{
+		"cluster":     {`powerstore_cluster{` + ip + `,global_id="PS000000000001",management_address="192.0.2.1",master_appliance_id="A1",name="fake-cluster"} 1`},
+		"appliance":   {`powerstore_appliance{` + ip + `,appliance_id="A1",service_tag="TAG0001"} 0`, `powerstore_perf_avg_latency{`},
+		"hardware":    {`powerstore_hardware_Fan_state{` + ip + `,appliance_id="A1",name="BaseEnclosure-Fan-0"} 1`, `powerstore_hardware_drive_size{`, `powerstore_wear_metrics_by_drive{`},
+		"port":        {`powerstore_eth_port_current_speed{` + ip + `,appliance_id="A1",eth_port_id="BaseEnclosure-NodeA-IoModule0-FEPort0"} 25`, `powerstore_metricFcPort_avg_latency{`, `powerstore_metricEthPort_avg_bytes_rx_ps{`},
+		"volume":      {`powerstore_volume_size{` + ip + `,appliance_id="A1",name="volume-A1-1"} 1.073741824e+11`, `powerstore_metricVolume_avg_latency{` + ip + `,appliance_id="A1",volume_id="volume-A1-1"}`},
+		"volumeGroup": {`powerstore_volumegroup_logical_used{` + ip + `,appliance_id="A1",name="vg-A1-1"} 2.147483648e+09`, `powerstore_metricVg_avg_latency{`},
+		"nas":         {`powerstore_nas_server_operational_status{` + ip + `,name="fake-nas-1"} 1`, `powerstore_metricNas_avg_size{`},
+		"file":        {`powerstore_filesystem_logical_used{`, `powerstore_metricFilesystem_avg_latency{`},
+		"capacity":    {`powerstore_cap_last_physical_total{` + ip + `,appliance_id="A1"}`},
+	}
+	for category, series := range expected {
+		metrics := e.scrape(t, category)
+		for _, prefix := range series {
+			if !strings.Contains(metrics, prefix) {
+				t.Errorf("%s metrics miss %s", category, prefix)
+			}
+		}
+		for _, line := range collectorSuccess(metrics) {
+			if !strings.HasSuffix(line, " 1") {
+				t.Errorf("%s collector failed: %s", category, line)
+			}
+		}
+	}
+}
+
+func TestEndToEndPagination(t *testing.T) {
+	topology := powerstorefake.DefaultTopology()
+	topology.Volumes = 7
+	fake := powerstorefake.NewServer(powerstorefake.Generate(topology))
+	t.Cleanup(fake.Close)
+	fake.SetPageSize(3)
+	e := newExporter(t, fake)
+	metrics := e.scrape(t, "volume")
+	for _, name := range []string{"volume-A1-1", "volume-A1-4", "volume-A1-7"} {
+		if !strings.Contains(metrics, `powerstore_volume_size{IP="`+e.storage.Ip+`",appliance_id="A1",name="`+name+`"}`) {
+			t.Errorf("the volume %s of a later page is missing", name)
+		}
+		if !strings.Contains(metrics, `volume_id="`+name+`"`) {
+			t.Errorf("the performance of the volume %s of a later page is missing", name)
+		}
+	}
+}
+
+func TestEndToEndSessionExpiry(t *testing.T) {
+	fake := powerstorefake.NewServer(powerstorefake.Generate(powerstorefake.DefaultTopology()))
+	t.Cleanup(fake.Close)
+	e := newExporter(t, fake)
+	logins := fake.Logins()
+	fake.ExpireSessions()
+	metrics := e.scrape(t, "cluster")
+	if !strings.Contains(metrics, `powerstore_scrape_collector_success{IP="`+e.storage.Ip+`",collector="cluster"} 1`) {
+		t.Errorf("the cluster collector failed after the session expired:\n%s", metrics)
+	}
+	if fake.Logins() != logins+1 {
+		t.Errorf("logins = %d, want one re-login after %d", fake.Logins(), logins)
+	}
+}
+
+func TestEndToEndErrors(t *testing.T) {
+	fake := powerstorefake.NewServer(powerstorefake.Generate(powerstorefake.DefaultTopology()))
+	t.Cleanup(fake.Close)
+	e := newExporter(t, fake)
+	success := `powerstore_scrape_collector_success{IP="` + e.storage.Ip + `",collector="volume"} `
+
+	fake.Fail("volume_list_cma_view", http.StatusInternalServerError, 1)
+	if metrics := e.scrape(t, "volume"); !strings.Contains(metrics, success+"0") {
+		t.Errorf("the volume collector did not report the 500:\n%s", metrics)
+	}
+
+	fake.Fail("volume_list_cma_view", http.StatusServiceUnavailable, 1)
+	if metrics := e.scrape(t, "volume"); !strings.Contains(metrics, success+"1") {
+		t.Errorf("the 503 was not retried:\n%s", metrics)
+	}
+}
+
+func TestShutdownStopsPolling(t *testing.T) {
+	fake := powerstorefake.NewServer(powerstorefake.Generate(powerstorefake.DefaultTopology()))
+	t.Cleanup(fake.Close)
+	config := &utils.Config{
+		Exporter: utils.Exporter{
+			ReqLimit:          10,
+			InventoryInterval: 1,
+			Polling:           utils.Polling{Enabled: true, Interval: 1},
+		},
+		StorageList: []utils.Storage{fake.Storage()},
+	}
+	_, shutdown, err := NewRouter(config, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for fake.Requests("cluster") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the cluster was not polled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not wait for the loops to return")
+	}
+	polled := fake.Requests("cluster")
+	time.Sleep(1500 * time.Millisecond)
+	if fake.Requests("cluster") != polled {
+		t.Errorf("the cluster was polled after the shutdown")
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package route
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	powerstorefake "powerstore-metrics-exporter/powerstore-fake"
+	"powerstore-metrics-exporter/utils"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordReplay(t *testing.T) {
+	fake := powerstorefake.NewServer(powerstorefake.Generate(powerstorefake.DefaultTopology()))
+	t.Cleanup(fake.Close)
+	fake.SetClock(func() time.Time { return goldenClock })
+	dir := t.TempDir()
+	categoryNames := make([]string, 0, len(categories))
+	for category := range categories {
+		categoryNames = append(categoryNames, category)
+	}
+	sort.Strings(categoryNames)
+
+	recording := startExporter(t, fake.Storage(), utils.Traffic{Record: dir})
+	recorded := make(map[string]string)
+	for _, category := range categoryNames {
+		recorded[category] = normalizeMetrics(recording.scrape(t, category), recording.storage.Ip)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no recording written in %s: %v", dir, err)
+	}
+	basicAuth := base64.StdEncoding.EncodeToString([]byte(powerstorefake.User + ":" + powerstorefake.Password))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{powerstorefake.Password, basicAuth, "auth_cookie", "Dell-Emc-Token", "fake-session"} {
+			if strings.Contains(strings.ToLower(string(content)), strings.ToLower(secret)) {
+				t.Errorf("the recording %s contains %s", filepath.Base(file), secret)
+			}
+		}
+	}
+
+	requests := fake.Requests("volume_list_cma_view") + fake.Requests("metrics/generate")
+	replaying := startExporter(t, fake.Storage(), utils.Traffic{Replay: dir})
+	for _, category := range categoryNames {
+		got := normalizeMetrics(replaying.scrape(t, category), replaying.storage.Ip)
+		if diff := diffLines(recorded[category], got); diff != "" {
+			t.Errorf("the replayed %s metrics differ from the recorded ones (-recorded +replayed):\n%s", category, diff)
+		}
+	}
+	if got := fake.Requests("volume_list_cma_view") + fake.Requests("metrics/generate"); got != requests {
+		t.Errorf("the replay sent %d requests to the powerstore", got-requests)
+	}
+}
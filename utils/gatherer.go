@@ -0,0 +1,137 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Exposition The metrics of one response, held back by the collectors until it is written.
+// In gap free mode the samples older than the newest one of a series are added as backfill, a registry only
+// accepting one sample per series, and the cursors of the collectors only move on Commit, so that the samples
+// of a response that is never written are collected again by the next scrape.
+type Exposition struct {
+	lock     sync.Mutex
+	backfill []*dto.MetricFamily
+	commits  []func()
+}
+
+type expositionKey struct{}
+
+func NewExposition() *Exposition {
+	return &Exposition{}
+}
+
+// WithExposition Returns the context of a collection exposed through the exposition
+func WithExposition(ctx context.Context, exposition *Exposition) context.Context {
+	return context.WithValue(ctx, expositionKey{}, exposition)
+}
+
+// ExpositionFrom Returns the exposition of the collection, nil when it is not exposed through one
+func ExpositionFrom(ctx context.Context) *Exposition {
+	exposition, _ := ctx.Value(expositionKey{}).(*Exposition)
+	return exposition
+}
+
+// Backfill Add older samples to the response
+func (e *Exposition) Backfill(families []*dto.MetricFamily) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.backfill = mergeFamilies(e.backfill, families)
+}
+
+// OnCommit Run commit once the response is written
+func (e *Exposition) OnCommit(commit func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.commits = append(e.commits, commit)
+}
+
+// Commit The response is written, run the commits of the collectors
+func (e *Exposition) Commit() {
+	e.lock.Lock()
+	commits := e.commits
+	e.commits = nil
+	e.lock.Unlock()
+	for _, commit := range commits {
+		commit()
+	}
+}
+
+// Gatherer Gathers the metrics of the gatherer together with the backfill, merged in before the newer samples of the same metric
+func (e *Exposition) Gatherer(gatherer prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+		e.lock.Lock()
+		backfill := e.backfill
+		e.lock.Unlock()
+		return mergeFamilies(backfill, families), err
+	})
+}
+
+// MergeGatherers Gathers the metrics of all the gatherers. Unlike prometheus.Gatherers the metrics are not checked
+// for consistency, which would drop the backfill, so the gatherers must not expose the same series.
+func MergeGatherers(gatherers ...prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		var result []*dto.MetricFamily
+		var errs prometheus.MultiError
+		for _, gatherer := range gatherers {
+			families, err := gatherer.Gather()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			result = mergeFamilies(result, families)
+		}
+		return result, errs.MaybeUnwrap()
+	})
+}
+
+// mergeFamilies Returns the families of both lists sorted by name, the metrics of a family in both lists are joined, first before second
+func mergeFamilies(first, second []*dto.MetricFamily) []*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(first)+len(second))
+	for _, families := range [][]*dto.MetricFamily{first, second} {
+		for _, family := range families {
+			merged, ok := byName[family.GetName()]
+			if !ok {
+				byName[family.GetName()] = family
+				continue
+			}
+			metrics := make([]*dto.Metric, 0, len(merged.Metric)+len(family.Metric))
+			metrics = append(metrics, merged.Metric...)
+			metrics = append(metrics, family.Metric...)
+			byName[family.GetName()] = &dto.MetricFamily{
+				Name:   merged.Name,
+				Help:   merged.Help,
+				Type:   merged.Type,
+				Metric: metrics,
+			}
+		}
+	}
+	result := make([]*dto.MetricFamily, 0, len(byName))
+	for _, family := range byName {
+		result = append(result, family)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result
+}
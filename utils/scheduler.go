@@ -0,0 +1,257 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Priority The order in which the waiting requests get a slot, the highest first
+type Priority int
+
+const (
+	// PriorityBulk The metrics/generate fan-out of the performance collectors
+	PriorityBulk Priority = iota
+	// PriorityNormal The collection queries of the collectors
+	PriorityNormal
+	// PriorityHigh The inventory and health calls, e.g. the id lists, the cluster, hardware and appliance status
+	PriorityHigh
+)
+
+var priorityNames = map[Priority]string{
+	PriorityBulk:   "bulk",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+}
+
+func (p Priority) String() string {
+	return priorityNames[p]
+}
+
+var (
+	schedulerQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "powerstore_scheduler_queue_depth",
+		Help: "Number of powerstore rest api requests waiting for a slot",
+	}, []string{"IP", "priority"})
+	schedulerRunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "powerstore_scheduler_running_requests",
+		Help: "Number of powerstore rest api requests holding a slot",
+	}, []string{"IP"})
+	schedulerWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "powerstore_scheduler_wait_seconds",
+		Help:    "Time the powerstore rest api requests waited for a slot,unit is s",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"IP", "priority"})
+)
+
+func init() {
+	prometheus.MustRegister(schedulerQueueDepth, schedulerRunning, schedulerWait)
+}
+
+// RequestScheduler The scheduler of the requests to all the powerstores
+var RequestScheduler *Scheduler
+
+// InitScheduler Create the request scheduler with the global cap of concurrent requests
+func InitScheduler(maxReq int) {
+	RequestScheduler = NewScheduler(maxReq)
+}
+
+// Scheduler Hands out the slots of the requests to the powerstores. A request runs when its array and
+// the global cap both have a free slot, the waiting requests get the freed slots by priority then in arrival order.
+type Scheduler struct {
+	lock    sync.Mutex
+	limit   int
+	running int
+	arrays  map[string]*arrayQueue
+	seq     uint64
+}
+
+type arrayQueue struct {
+	// limit The slots of the array, its configured limit or else its share of the global cap
+	limit      int
+	configured int
+	running    int
+	waiting    [PriorityHigh + 1][]*waiter
+}
+
+type waiter struct {
+	seq   uint64
+	ready chan struct{}
+}
+
+func NewScheduler(limit int) *Scheduler {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &Scheduler{limit: limit, arrays: make(map[string]*arrayQueue)}
+}
+
+// SetArrayLimit Set the concurrent requests allowed to the array, at most the global cap. When limit is not positive
+// the array gets an equal share of the global cap, so that a slow array cannot hold every slot
+func (s *Scheduler) SetArrayLimit(array string, limit int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if limit <= 0 {
+		limit = 0
+	} else if limit > s.limit {
+		limit = s.limit
+	}
+	s.queue(array).configured = limit
+	s.share()
+	s.dispatch()
+}
+
+// share Set the limit of the arrays without configured limit to an equal share of the global cap, the lock must be held
+func (s *Scheduler) share() {
+	fair := s.limit / len(s.arrays)
+	if fair < 1 {
+		fair = 1
+	}
+	for _, queue := range s.arrays {
+		if queue.configured > 0 {
+			queue.limit = queue.configured
+		} else {
+			queue.limit = fair
+		}
+	}
+}
+
+// Acquire Wait for a slot of the array, the returned release must be called once the request is done
+func (s *Scheduler) Acquire(ctx context.Context, array string, priority Priority) (func(), error) {
+	startTime := time.Now()
+	s.lock.Lock()
+	queue := s.queue(array)
+	if s.running < s.limit && queue.running < queue.limit && !queue.hasWaiting(priority) {
+		s.start(array, queue)
+		s.lock.Unlock()
+		schedulerWait.WithLabelValues(array, priority.String()).Observe(0)
+		return s.releaser(array, queue), nil
+	}
+	s.seq++
+	w := &waiter{seq: s.seq, ready: make(chan struct{})}
+	queue.waiting[priority] = append(queue.waiting[priority], w)
+	schedulerQueueDepth.WithLabelValues(array, priority.String()).Inc()
+	s.lock.Unlock()
+
+	select {
+	case <-w.ready:
+		schedulerWait.WithLabelValues(array, priority.String()).Observe(time.Since(startTime).Seconds())
+		return s.releaser(array, queue), nil
+	case <-ctx.Done():
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if queue.remove(priority, w) {
+			schedulerQueueDepth.WithLabelValues(array, priority.String()).Dec()
+			return nil, ctx.Err()
+		}
+		// the slot was handed out at the same time, give it to the next request
+		s.finish(array, queue)
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Scheduler) queue(array string) *arrayQueue {
+	queue, ok := s.arrays[array]
+	if !ok {
+		queue = &arrayQueue{}
+		s.arrays[array] = queue
+		s.share()
+	}
+	return queue
+}
+
+func (s *Scheduler) releaser(array string, queue *arrayQueue) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			s.finish(array, queue)
+		})
+	}
+}
+
+// start Take a slot, the lock must be held
+func (s *Scheduler) start(array string, queue *arrayQueue) {
+	s.running++
+	queue.running++
+	schedulerRunning.WithLabelValues(array).Inc()
+}
+
+// finish Free a slot and hand out the free slots, the lock must be held
+func (s *Scheduler) finish(array string, queue *arrayQueue) {
+	s.running--
+	queue.running--
+	schedulerRunning.WithLabelValues(array).Dec()
+	s.dispatch()
+}
+
+// dispatch Hand out the free slots to the waiting requests, the lock must be held
+func (s *Scheduler) dispatch() {
+	for s.running < s.limit {
+		var next *arrayQueue
+		var nextArray string
+		var nextPriority Priority
+		for array, queue := range s.arrays {
+			if queue.running >= queue.limit {
+				continue
+			}
+			for priority := PriorityHigh; priority >= PriorityBulk; priority-- {
+				if len(queue.waiting[priority]) == 0 {
+					continue
+				}
+				if next == nil || priority > nextPriority ||
+					priority == nextPriority && queue.waiting[priority][0].seq < next.waiting[nextPriority][0].seq {
+					next, nextArray, nextPriority = queue, array, priority
+				}
+				break
+			}
+		}
+		if next == nil {
+			return
+		}
+		w := next.waiting[nextPriority][0]
+		next.waiting[nextPriority] = next.waiting[nextPriority][1:]
+		schedulerQueueDepth.WithLabelValues(nextArray, nextPriority.String()).Dec()
+		s.start(nextArray, next)
+		close(w.ready)
+	}
+}
+
+// hasWaiting Whether requests of the priority or a higher one are waiting
+func (q *arrayQueue) hasWaiting(priority Priority) bool {
+	for p := priority; p <= PriorityHigh; p++ {
+		if len(q.waiting[p]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *arrayQueue) remove(priority Priority, w *waiter) bool {
+	for i, waiting := range q.waiting[priority] {
+		if waiting == w {
+			q.waiting[priority] = append(q.waiting[priority][:i], q.waiting[priority][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
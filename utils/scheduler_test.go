@@ -0,0 +1,199 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued Wait until the number of requests waiting for a slot of the array reaches n
+func waitQueued(t *testing.T, s *Scheduler, array string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.lock.Lock()
+		queued := 0
+		if queue, ok := s.arrays[array]; ok {
+			for _, waiting := range queue.waiting {
+				queued += len(waiting)
+			}
+		}
+		s.lock.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests queued for %s, want %d", queued, array, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	s := NewScheduler(1)
+	release, err := s.Acquire(context.Background(), "array", PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+	// queued from the lowest priority to the highest, each waits until the previous one is queued
+	for i, priority := range []Priority{PriorityBulk, PriorityBulk, PriorityNormal, PriorityHigh} {
+		wg.Add(1)
+		go func(priority Priority) {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), "array", priority)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			lock.Lock()
+			order = append(order, priority)
+			lock.Unlock()
+			release()
+		}(priority)
+		waitQueued(t, s, "array", i+1)
+	}
+	release()
+	wg.Wait()
+	want := []Priority{PriorityHigh, PriorityNormal, PriorityBulk, PriorityBulk}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("slots handed out in the order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSchedulerArrayLimit(t *testing.T) {
+	s := NewScheduler(10)
+	s.SetArrayLimit("slow", 2)
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := s.Acquire(context.Background(), "slow", PriorityNormal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, "slow", PriorityHigh); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third request to the array got %v, want to wait beyond its deadline", err)
+	}
+
+	// the other arrays are not held up by the busy one
+	release, err := s.Acquire(context.Background(), "fast", PriorityBulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	releases[0]()
+	release, err = s.Acquire(context.Background(), "slow", PriorityNormal)
+	if err != nil {
+		t.Fatalf("a slot freed on the array was not handed out: %v", err)
+	}
+	release()
+	releases[1]()
+}
+
+func TestSchedulerGlobalLimit(t *testing.T) {
+	s := NewScheduler(1)
+	release, err := s.Acquire(context.Background(), "a", PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, "b", PriorityHigh); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request beyond the global limit got %v, want to wait", err)
+	}
+	release()
+}
+
+func TestSchedulerCancelWhileQueued(t *testing.T) {
+	s := NewScheduler(1)
+	release, err := s.Acquire(context.Background(), "array", PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, "array", PriorityHigh)
+		done <- err
+	}()
+	waitQueued(t, s, "array", 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled request got %v, want context.Canceled", err)
+	}
+	waitQueued(t, s, "array", 0)
+
+	release()
+	// the slot of the cancelled request is not leaked
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err = s.Acquire(ctx, "array", PriorityBulk)
+	if err != nil {
+		t.Fatalf("the slot was lost after the cancellation: %v", err)
+	}
+	release()
+	if s.running != 0 {
+		t.Errorf("running = %d after every release, want 0", s.running)
+	}
+}
+
+func TestSchedulerFairShare(t *testing.T) {
+	s := NewScheduler(4)
+	s.SetArrayLimit("slow", 0)
+	s.SetArrayLimit("fast", 0)
+	// the slow array holds its share of the slots and queues more requests
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := s.Acquire(context.Background(), "slow", PriorityHigh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, "slow", PriorityHigh); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request beyond the share of the slow array got %v, want to wait", err)
+	}
+
+	// the other array still gets its share
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		release, err := s.Acquire(ctx, "fast", PriorityBulk)
+		if err != nil {
+			t.Fatalf("the saturated array starved the other one: %v", err)
+		}
+		releases = append(releases, release)
+	}
+	for _, release := range releases {
+		release()
+	}
+}
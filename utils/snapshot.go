@@ -0,0 +1,120 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	snapshotAgeName       = "powerstore_snapshot_age_seconds"
+	snapshotAgeHelp       = "Seconds since the served metrics were collected from the powerstore"
+	snapshotTimestampName = "powerstore_snapshot_timestamp_seconds"
+	snapshotTimestampHelp = "Unix time the served metrics were collected from the powerstore"
+)
+
+// Snapshot A prometheus.Gatherer serving the result of the last poll of a gatherer, so that scrapes never reach the powerstore
+type Snapshot struct {
+	gatherer      prometheus.Gatherer
+	ageDesc       *prometheus.Desc
+	timestampDesc *prometheus.Desc
+	lock          sync.RWMutex
+	families      []*dto.MetricFamily
+	collected     time.Time
+}
+
+func NewSnapshot(gatherer prometheus.Gatherer, constLabels prometheus.Labels) *Snapshot {
+	return &Snapshot{
+		gatherer:      gatherer,
+		ageDesc:       prometheus.NewDesc(snapshotAgeName, snapshotAgeHelp, nil, constLabels),
+		timestampDesc: prometheus.NewDesc(snapshotTimestampName, snapshotTimestampHelp, nil, constLabels),
+	}
+}
+
+// Poll Refresh the snapshot now and then every interval until ctx is cancelled
+func (s *Snapshot) Poll(ctx context.Context, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Refresh(logger)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh Gather the metrics and replace the snapshot, every series is stamped with the collection time
+func (s *Snapshot) Refresh(logger log.Logger) {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		level.Warn(logger).Log("msg", "gather snapshot error", "err", err)
+	}
+	collected := time.Now()
+	timestampMs := collected.UnixNano() / int64(time.Millisecond)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			if metric.TimestampMs == nil {
+				metric.TimestampMs = &timestampMs
+			}
+		}
+	}
+	s.lock.Lock()
+	s.families = families
+	s.collected = collected
+	s.lock.Unlock()
+}
+
+// Gather Returns the last snapshot with its age, nothing is served before the first poll is finished
+func (s *Snapshot) Gather() ([]*dto.MetricFamily, error) {
+	s.lock.RLock()
+	families, collected := s.families, s.collected
+	s.lock.RUnlock()
+	if collected.IsZero() {
+		return nil, nil
+	}
+	result := make([]*dto.MetricFamily, 0, len(families)+2)
+	result = append(result, families...)
+	result = append(result,
+		gaugeFamily(snapshotAgeName, snapshotAgeHelp,
+			prometheus.MustNewConstMetric(s.ageDesc, prometheus.GaugeValue, time.Since(collected).Seconds())),
+		gaugeFamily(snapshotTimestampName, snapshotTimestampHelp,
+			prometheus.MustNewConstMetric(s.timestampDesc, prometheus.GaugeValue, float64(collected.UnixNano())/1e9)),
+	)
+	return result, nil
+}
+
+func gaugeFamily(name, help string, metric prometheus.Metric) *dto.MetricFamily {
+	pb := &dto.Metric{}
+	if err := metric.Write(pb); err != nil {
+		panic(err)
+	}
+	return &dto.MetricFamily{
+		Name:   &name,
+		Help:   &help,
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{pb},
+	}
+}
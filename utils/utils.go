@@ -17,6 +17,7 @@
 package utils
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -24,27 +25,106 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"io/ioutil"
 	stdlog "log"
-)
+	"net/http"
+	"time"
 
-var (
-	ReqCounter chan int
+	"gopkg.in/yaml.v3"
 )
 
-func InitReqCounter(MaxReq int) {
-	ReqCounter = make(chan int, MaxReq)
-}
-
 type Storage struct {
 	Ip       string `yaml:"ip"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	Version  string `yaml:"apiVersion"`
 	Limit    int    `yaml:"apiLimit"`
+	// ReqLimit The concurrent requests to the powerstore, within the exporter reqLimit
+	ReqLimit int `yaml:"reqLimit"`
+	// CacheTTL Seconds the responses of the powerstore are reused for, 0 only shares the identical requests in flight
+	CacheTTL float64 `yaml:"cacheTTL"`
+	TLS      TLS     `yaml:"tls"`
+	Retry    Retry   `yaml:"retry"`
+	Traffic  Traffic `yaml:"-"`
+}
+
+// Traffic Where the requests to the powerstores are recorded or replayed from, set by the -record and -replay flags
+type Traffic struct {
+	// Record Directory the sanitized requests and responses of each powerstore are written to
+	Record string
+	// Replay Directory of the recordings answering the requests instead of the powerstores
+	Replay string
+}
+
+// Retry How the failed idempotent requests to a powerstore are retried, e.g. on 503 or a connection reset
+type Retry struct {
+	// Attempts The number of attempts of a request, 1 disables the retries, 3 by default
+	Attempts int `yaml:"attempts"`
+	// InitialBackoff Seconds before the first retry, doubled for every next retry up to MaxBackoff
+	InitialBackoff float64 `yaml:"initialBackoff"`
+	MaxBackoff     float64 `yaml:"maxBackoff"`
+}
+
+// TLS How the certificate of a powerstore is verified, against the system roots when nothing is set
+type TLS struct {
+	// CAFile PEM bundle of the certificate authorities trusted instead of the system roots
+	CAFile string `yaml:"caFile"`
+	// ServerName The name verified in the certificate when it differs from the ip
+	ServerName string `yaml:"serverName"`
+	// Fingerprint SHA-256 of the powerstore certificate in hex, verifying it replaces the chain verification unless caFile is set
+	Fingerprint string `yaml:"fingerprint"`
+	// MinVersion The lowest TLS version accepted, 1.0, 1.1, 1.2 or 1.3
+	MinVersion         string `yaml:"minVersion"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
 }
 
 type Exporter struct {
-	Port     int `yaml:"port"`
-	ReqLimit int `yaml:"reqLimit"`
+	Port              int     `yaml:"port"`
+	ReqLimit          int     `yaml:"reqLimit"`
+	InventoryInterval int     `yaml:"inventoryInterval"`
+	Polling           Polling `yaml:"polling"`
+}
+
+// Polling Background collection of each metrics category, scrapes are then served from the last snapshot
+type Polling struct {
+	Enabled   bool           `yaml:"enabled"`
+	Interval  int            `yaml:"interval"`
+	Intervals map[string]int `yaml:"intervals"`
+}
+
+// IntervalOf Returns the polling interval of the metrics category
+func (p Polling) IntervalOf(category string) time.Duration {
+	if interval, ok := p.Intervals[category]; ok && interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return time.Duration(p.Interval) * time.Second
+}
+
+// MaxPollingInterval The polling intervals must stay below the 5m lookback of prometheus,
+// the series are stamped with their collection time and would go stale between two polls
+const MaxPollingInterval = 5 * time.Minute
+
+// Check Returns an error when the interval of a category reaches MaxPollingInterval
+func (p Polling) Check() error {
+	if !p.Enabled {
+		return nil
+	}
+	if p.IntervalOf("") >= MaxPollingInterval {
+		return fmt.Errorf("polling interval %ds must be below %s", p.Interval, MaxPollingInterval)
+	}
+	for category := range p.Intervals {
+		if interval := p.IntervalOf(category); interval >= MaxPollingInterval {
+			return fmt.Errorf("polling interval of %s %s must be below %s", category, interval, MaxPollingInterval)
+		}
+	}
+	return nil
+}
+
+// Metrics How the samples returned by metrics/generate are exposed
+type Metrics struct {
+	SampleTimestamps bool `yaml:"sampleTimestamps"`
+	// GapFree Expose every sample newer than the last one exposed for the entity, implies SampleTimestamps
+	GapFree bool `yaml:"gapFree"`
+	// Intervals The metrics/generate interval by collector, Twenty_Sec, Five_Mins, One_Hour or One_Day
+	Intervals map[string]string `yaml:"intervals"`
 }
 
 type Logs struct {
@@ -57,6 +137,8 @@ type Config struct {
 	Exporter    Exporter  `yaml:"exporter"`
 	StorageList []Storage `yaml:"storageList"`
 	Log         Logs      `yaml:"log"`
+	Metrics     Metrics   `yaml:"metrics"`
+	Traffic     Traffic   `yaml:"-"`
 }
 
 func GetConfig(configPath string) *Config {
@@ -69,16 +151,47 @@ func GetConfig(configPath string) *Config {
 	if err != nil {
 		stdlog.Fatalf("Error Unmarshal yamL file: %s\n", err)
 	}
+	if config.Exporter.InventoryInterval <= 0 {
+		config.Exporter.InventoryInterval = 600
+	}
+	if config.Exporter.Polling.Interval <= 0 {
+		config.Exporter.Polling.Interval = 60
+	}
 	return &config
 }
 
-func PrometheusHandler(registry *prometheus.Registry, logger log.Logger) gin.HandlerFunc {
+// PrometheusHandler Serves the metrics of the gatherer, commit is called once they are written, it may be nil
+func PrometheusHandler(gatherer prometheus.Gatherer, commit func(), logger log.Logger) gin.HandlerFunc {
 	handlerOpts := promhttp.HandlerOpts{
 		ErrorLog:      stdlog.New(log.NewStdlibAdapter(level.Error(logger)), "", 0),
 		ErrorHandling: promhttp.ContinueOnError,
 	}
-	h := promhttp.HandlerFor(registry, handlerOpts)
+	h := promhttp.HandlerFor(gatherer, handlerOpts)
 	return func(context *gin.Context) {
-		h.ServeHTTP(context.Writer, context.Request)
+		writer := &writeRecorder{ResponseWriter: context.Writer}
+		h.ServeHTTP(writer, context.Request)
+		if commit == nil {
+			return
+		}
+		// the scraper went away or the response could not be sent, the metrics are exposed again by the next scrape
+		if writer.err != nil || context.Request.Context().Err() != nil || context.Writer.Status() != http.StatusOK {
+			level.Debug(logger).Log("msg", "the metrics were not written, they are kept for the next scrape", "uri", context.Request.RequestURI)
+			return
+		}
+		commit()
+	}
+}
+
+// writeRecorder Keeps the first error of the writes of the response
+type writeRecorder struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if err != nil && w.err == nil {
+		w.err = err
 	}
+	return n, err
 }
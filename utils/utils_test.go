@@ -0,0 +1,40 @@
+/*
+ Copyright (c) 2024-2025 Dell Inc. or its subsidiaries. All Rights Reserved.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import "testing"
+
+func TestPollingCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		polling Polling
+		wantErr bool
+	}{
+		{"disabled", Polling{Interval: 600}, false},
+		{"default", Polling{Enabled: true, Interval: 60}, false},
+		{"below the lookback", Polling{Enabled: true, Interval: 60, Intervals: map[string]int{"capacity": 299}}, false},
+		{"unset category", Polling{Enabled: true, Interval: 60, Intervals: map[string]int{"cluster": 0}}, false},
+		{"default at the lookback", Polling{Enabled: true, Interval: 300}, true},
+		{"category at the lookback", Polling{Enabled: true, Interval: 60, Intervals: map[string]int{"cluster": 300}}, true},
+		{"category above the lookback", Polling{Enabled: true, Interval: 60, Intervals: map[string]int{"capacity": 3600}}, true},
+	}
+	for _, test := range tests {
+		if err := test.polling.Check(); (err != nil) != test.wantErr {
+			t.Errorf("%s: Check() = %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+}